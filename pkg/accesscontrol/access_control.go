@@ -25,8 +25,8 @@ func (a *AccessControl) CanDo(apiOp *types.APIRequest, resource, verb, namespace
 		}
 	}
 	group, resource := kv.Split(resource, "/")
-	accessSet := apiOp.Schemas.Attributes["accessSet"].(*AccessSet)
-	if accessSet.Grants(verb, schema.GroupResource{
+	accessSet := AccessSetFromAPIRequest(apiOp)
+	if accessSet != nil && accessSet.Grants(verb, schema.GroupResource{
 		Group:    group,
 		Resource: resource,
 	}, namespace, name) {
@@ -35,6 +35,15 @@ func (a *AccessControl) CanDo(apiOp *types.APIRequest, resource, verb, namespace
 	return a.SchemaBasedAccess.CanDo(apiOp, resource, verb, namespace, name)
 }
 
+// AccessSetFromAPIRequest returns the AccessSet of the user making the request
+func AccessSetFromAPIRequest(apiOp *types.APIRequest) *AccessSet {
+	if apiOp == nil || apiOp.Schemas == nil {
+		return nil
+	}
+	accessSet, _ := apiOp.Schemas.Attributes["accessSet"].(*AccessSet)
+	return accessSet
+}
+
 func (a *AccessControl) CanWatch(apiOp *types.APIRequest, schema *types.APISchema) error {
 	if attributes.GVK(schema).Kind != "" {
 		access := GetAccessListMap(schema)
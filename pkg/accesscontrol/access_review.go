@@ -0,0 +1,106 @@
+package accesscontrol
+
+import (
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+type RBACRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// RuleGrant is a rule a subject is granted in a namespace, All for every namespace, through a
+// binding to a role
+type RuleGrant struct {
+	Subject   rbacv1.Subject
+	Namespace string
+	Binding   RBACRef
+	Role      RBACRef
+	Rule      rbacv1.PolicyRule
+}
+
+// Allows is whether the rule of the grant allows the verb on the resource. Rules with
+// resource names never allow a request without a name, as in Kubernetes.
+func (r RuleGrant) Allows(verb string, gr schema.GroupResource, name string) bool {
+	if !matches(r.Rule.Verbs, verb) || !matches(r.Rule.APIGroups, gr.Group) || !matches(r.Rule.Resources, gr.Resource) {
+		return false
+	}
+	if len(r.Rule.ResourceNames) == 0 {
+		return true
+	}
+	return name != "" && matches(r.Rule.ResourceNames, name)
+}
+
+func matches(values []string, value string) bool {
+	for _, v := range values {
+		if v == All || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Explainer explains access with the bindings and roles granting it
+type Explainer interface {
+	// RuleGrants returns the rules the user and its groups are granted
+	RuleGrants(user user.Info) []RuleGrant
+	// SubjectGrants returns the grants of every subject allowed the verb on the resource
+	SubjectGrants(verb string, gr schema.GroupResource, namespace, name string) []RuleGrant
+}
+
+// RuleGrants returns the rules the user and its groups are granted along with the bindings
+// and roles granting them
+func (l *AccessStore) RuleGrants(user user.Info) []RuleGrant {
+	result := l.users.ruleGrants(user.GetName())
+	for _, group := range user.GetGroups() {
+		result = append(result, l.groups.ruleGrants(group)...)
+	}
+	return result
+}
+
+// SubjectGrants returns the grants of every subject allowed the verb on the resource. An
+// empty namespace only considers cluster wide grants.
+func (l *AccessStore) SubjectGrants(verb string, gr schema.GroupResource, namespace, name string) (result []RuleGrant) {
+	// the users index sees every binding, the subject kind is not filtered on
+	p := l.users
+
+	crbs, err := p.crbCache.List(labels.Everything())
+	if err == nil {
+		sort.Slice(crbs, func(i, j int) bool {
+			return crbs[i].Name < crbs[j].Name
+		})
+		for _, crb := range crbs {
+			for _, grant := range p.bindingGrants(All, crb.Subjects, RBACRef{Kind: "ClusterRoleBinding", Name: crb.Name}, crb.RoleRef) {
+				if grant.Allows(verb, gr, name) {
+					result = append(result, grant)
+				}
+			}
+		}
+	}
+
+	if namespace == "" {
+		return result
+	}
+
+	rbs, err := p.rbCache.List(namespace, labels.Everything())
+	if err == nil {
+		sort.Slice(rbs, func(i, j int) bool {
+			return rbs[i].Name < rbs[j].Name
+		})
+		for _, rb := range rbs {
+			for _, grant := range p.bindingGrants(rb.Namespace, rb.Subjects, RBACRef{Kind: "RoleBinding", Namespace: rb.Namespace, Name: rb.Name}, rb.RoleRef) {
+				if grant.Allows(verb, gr, name) {
+					result = append(result, grant)
+				}
+			}
+		}
+	}
+
+	return result
+}
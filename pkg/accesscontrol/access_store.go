@@ -5,8 +5,10 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"sort"
+	"sync"
 	"time"
 
+	"github.com/rancher/steve/pkg/metrics"
 	v1 "github.com/rancher/wrangler/pkg/generated/controllers/rbac/v1"
 	"k8s.io/apimachinery/pkg/util/cache"
 	"k8s.io/apiserver/pkg/authentication/user"
@@ -20,6 +22,12 @@ type AccessStore struct {
 	users  *policyRuleIndex
 	groups *policyRuleIndex
 	cache  *cache.LRUExpireCache
+	synced []func() bool
+
+	watchersLock sync.Mutex
+	watchers     map[int]*accessWatcher
+	watcherID    int
+	changed      chan struct{}
 }
 
 type roleKey struct {
@@ -32,10 +40,26 @@ func NewAccessStore(ctx context.Context, cacheResults bool, rbac v1.Interface) *
 	as := &AccessStore{
 		users:  newPolicyRuleIndex(true, revisions, rbac),
 		groups: newPolicyRuleIndex(false, revisions, rbac),
+		synced: []func() bool{
+			rbac.ClusterRole().Informer().HasSynced,
+			rbac.Role().Informer().HasSynced,
+			rbac.ClusterRoleBinding().Informer().HasSynced,
+			rbac.RoleBinding().Informer().HasSynced,
+		},
+		watchers: map[int]*accessWatcher{},
+		changed:  make(chan struct{}, 1),
 	}
 	if cacheResults {
 		as.cache = cache.NewLRUExpireCache(50)
 	}
+
+	// registered after the role revisions so they are current when watchers are checked
+	rbac.Role().OnChange(ctx, "access-store-watch", as.onRoleChanged)
+	rbac.ClusterRole().OnChange(ctx, "access-store-watch", as.onClusterRoleChanged)
+	rbac.RoleBinding().OnChange(ctx, "access-store-watch", as.onRoleBindingChanged)
+	rbac.ClusterRoleBinding().OnChange(ctx, "access-store-watch", as.onClusterRoleBindingChanged)
+	go as.checkWatchers(ctx)
+
 	return as
 }
 
@@ -44,6 +68,7 @@ func (l *AccessStore) AccessFor(user user.Info) *AccessSet {
 	if l.cache != nil {
 		cacheKey = l.CacheKey(user)
 		val, ok := l.cache.Get(cacheKey)
+		metrics.IncAccessSetCache(ok)
 		if ok {
 			as, _ := val.(*AccessSet)
 			return as
@@ -63,6 +88,16 @@ func (l *AccessStore) AccessFor(user user.Info) *AccessSet {
 	return result
 }
 
+// HasSynced is whether the roles and bindings access is computed from are cached
+func (l *AccessStore) HasSynced() bool {
+	for _, synced := range l.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
 func (l *AccessStore) CacheKey(user user.Info) string {
 	d := sha256.New()
 
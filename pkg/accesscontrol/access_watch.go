@@ -0,0 +1,148 @@
+package accesscontrol
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+// AccessSetNotifier is implemented by lookups that can tell when the access of a user changes
+type AccessSetNotifier interface {
+	// WatchAccess returns a channel that receives when the access of the user changes, it is
+	// closed once ctx is done
+	WatchAccess(ctx context.Context, user user.Info) <-chan struct{}
+}
+
+// WatchAccess returns a channel that receives when the access of the user changes. Lookups
+// that do not implement AccessSetNotifier are polled at interval.
+func WatchAccess(ctx context.Context, asl AccessSetLookup, user user.Info, interval time.Duration) <-chan struct{} {
+	if notifier, ok := asl.(AccessSetNotifier); ok {
+		return notifier.WatchAccess(ctx, user)
+	}
+
+	result := make(chan struct{}, 1)
+	go func() {
+		defer close(result)
+		as := asl.AccessFor(user)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+
+			newAS := asl.AccessFor(user)
+			if newAS.ID != as.ID {
+				as = newAS
+				select {
+				case result <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return result
+}
+
+type accessWatcher struct {
+	user     user.Info
+	cacheKey string
+	c        chan struct{}
+}
+
+// WatchAccess notifies when the roles or bindings of the user change. The cache keys of the
+// watched users are recomputed when the role and binding informers see a change so only the
+// watchers whose access changed are notified.
+func (l *AccessStore) WatchAccess(ctx context.Context, user user.Info) <-chan struct{} {
+	w := &accessWatcher{
+		user:     user,
+		cacheKey: l.CacheKey(user),
+		c:        make(chan struct{}, 1),
+	}
+
+	l.watchersLock.Lock()
+	id := l.watcherID
+	l.watcherID++
+	l.watchers[id] = w
+	l.watchersLock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		l.watchersLock.Lock()
+		delete(l.watchers, id)
+		close(w.c)
+		l.watchersLock.Unlock()
+	}()
+
+	return w.c
+}
+
+func (l *AccessStore) onRoleChanged(key string, role *rbacv1.Role) (*rbacv1.Role, error) {
+	l.queueCheck()
+	return role, nil
+}
+
+func (l *AccessStore) onClusterRoleChanged(key string, role *rbacv1.ClusterRole) (*rbacv1.ClusterRole, error) {
+	l.queueCheck()
+	return role, nil
+}
+
+func (l *AccessStore) onRoleBindingChanged(key string, binding *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+	l.queueCheck()
+	return binding, nil
+}
+
+func (l *AccessStore) onClusterRoleBindingChanged(key string, binding *rbacv1.ClusterRoleBinding) (*rbacv1.ClusterRoleBinding, error) {
+	l.queueCheck()
+	return binding, nil
+}
+
+// queueCheck asks for the watchers to be checked, changes that arrive while a check is
+// queued are handled by that check
+func (l *AccessStore) queueCheck() {
+	select {
+	case l.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (l *AccessStore) checkWatchers(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.changed:
+		}
+
+		l.watchersLock.Lock()
+		// the cache key is computed once per user however many watchers they have
+		cacheKeys := map[string]string{}
+		for _, w := range l.watchers {
+			id := userID(w.user)
+			cacheKey, ok := cacheKeys[id]
+			if !ok {
+				cacheKey = l.CacheKey(w.user)
+				cacheKeys[id] = cacheKey
+			}
+			if cacheKey == w.cacheKey {
+				continue
+			}
+			w.cacheKey = cacheKey
+			select {
+			case w.c <- struct{}{}:
+			default:
+			}
+		}
+		l.watchersLock.Unlock()
+	}
+}
+
+func userID(user user.Info) string {
+	groups := append([]string{}, user.GetGroups()...)
+	sort.Strings(groups)
+	return user.GetName() + "\x00" + strings.Join(groups, "\x00")
+}
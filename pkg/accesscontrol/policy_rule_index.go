@@ -49,12 +49,12 @@ func newPolicyRuleIndex(user bool, revisions *roleRevisionIndex, rbac v1.Interfa
 }
 
 func (p *policyRuleIndex) clusterRoleBindingBySubjectIndexer(crb *rbacv1.ClusterRoleBinding) (result []string, err error) {
+	if crb.RoleRef.Kind != "ClusterRole" {
+		return nil, nil
+	}
 	for _, subject := range crb.Subjects {
-		if subject.APIGroup == rbacGroup && subject.Kind == p.kind && crb.RoleRef.Kind == "ClusterRole" {
-			result = append(result, subject.Name)
-		} else if subject.APIGroup == "" && p.kind == "User" && subject.Kind == "ServiceAccount" && subject.Namespace != "" && crb.RoleRef.Kind == "ClusterRole" {
-			// Index is for Users and this references a service account
-			result = append(result, fmt.Sprintf("serviceaccount:%s:%s", subject.Namespace, subject.Name))
+		if name, ok := p.subjectName(subject); ok {
+			result = append(result, name)
 		}
 	}
 	return
@@ -62,16 +62,35 @@ func (p *policyRuleIndex) clusterRoleBindingBySubjectIndexer(crb *rbacv1.Cluster
 
 func (p *policyRuleIndex) roleBindingBySubject(rb *rbacv1.RoleBinding) (result []string, err error) {
 	for _, subject := range rb.Subjects {
-		if subject.APIGroup == rbacGroup && subject.Kind == p.kind {
-			result = append(result, subject.Name)
-		} else if subject.APIGroup == "" && p.kind == "User" && subject.Kind == "ServiceAccount" && subject.Namespace != "" {
-			// Index is for Users and this references a service account
-			result = append(result, fmt.Sprintf("serviceaccount:%s:%s", subject.Namespace, subject.Name))
+		if name, ok := p.subjectName(subject); ok {
+			result = append(result, name)
 		}
 	}
 	return
 }
 
+// subjectName returns the name a binding subject is indexed by, false if the index is not
+// for subjects of its kind
+func (p *policyRuleIndex) subjectName(subject rbacv1.Subject) (string, bool) {
+	if subject.APIGroup == rbacGroup && subject.Kind == p.kind {
+		return subject.Name, true
+	} else if subject.APIGroup == "" && p.kind == "User" && subject.Kind == "ServiceAccount" && subject.Namespace != "" {
+		// Index is for Users and this references a service account
+		return fmt.Sprintf("serviceaccount:%s:%s", subject.Namespace, subject.Name), true
+	}
+	return "", false
+}
+
+// bindingSubjects returns the subjects of a binding that are indexed as subjectName
+func (p *policyRuleIndex) bindingSubjects(subjects []rbacv1.Subject, subjectName string) (result []rbacv1.Subject) {
+	for _, subject := range subjects {
+		if name, ok := p.subjectName(subject); ok && name == subjectName {
+			result = append(result, subject)
+		}
+	}
+	return result
+}
+
 var null = []byte{'\x00'}
 
 func (p *policyRuleIndex) addRolesToHash(digest hash.Hash, subjectName string) {
@@ -176,3 +195,39 @@ func (p *policyRuleIndex) getRoleBindings(subjectName string) []*rbacv1.RoleBind
 	})
 	return result
 }
+
+// ruleGrants returns the rules granted to the subject by each of its bindings, the subject
+// of a grant is the subject as the binding names it, such as a service account
+func (p *policyRuleIndex) ruleGrants(subjectName string) (result []RuleGrant) {
+	for _, binding := range p.getClusterRoleBindings(subjectName) {
+		subjects := p.bindingSubjects(binding.Subjects, subjectName)
+		result = append(result, p.bindingGrants(All, subjects, RBACRef{Kind: "ClusterRoleBinding", Name: binding.Name}, binding.RoleRef)...)
+	}
+
+	for _, binding := range p.getRoleBindings(subjectName) {
+		subjects := p.bindingSubjects(binding.Subjects, subjectName)
+		result = append(result, p.bindingGrants(binding.Namespace, subjects, RBACRef{Kind: "RoleBinding", Namespace: binding.Namespace, Name: binding.Name}, binding.RoleRef)...)
+	}
+
+	return result
+}
+
+func (p *policyRuleIndex) bindingGrants(namespace string, subjects []rbacv1.Subject, binding RBACRef, roleRef rbacv1.RoleRef) (result []RuleGrant) {
+	role := RBACRef{Kind: roleRef.Kind, Name: roleRef.Name}
+	if roleRef.Kind == "Role" {
+		role.Namespace = namespace
+	}
+
+	for _, rule := range p.getRules(namespace, roleRef) {
+		for _, subject := range subjects {
+			result = append(result, RuleGrant{
+				Subject:   subject,
+				Namespace: namespace,
+				Binding:   binding,
+				Role:      role,
+				Rule:      rule,
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package accesscontrol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	userSubject           = rbacv1.Subject{APIGroup: rbacGroup, Kind: "User", Name: "alice"}
+	groupSubject          = rbacv1.Subject{APIGroup: rbacGroup, Kind: "Group", Name: "alice"}
+	serviceAccountSubject = rbacv1.Subject{Kind: "ServiceAccount", Namespace: "default", Name: "alice"}
+)
+
+func TestBindingSubjects(t *testing.T) {
+	tests := []struct {
+		name        string
+		kind        string
+		subjects    []rbacv1.Subject
+		subjectName string
+		want        []rbacv1.Subject
+	}{
+		{
+			name:        "user",
+			kind:        "User",
+			subjects:    []rbacv1.Subject{userSubject, groupSubject},
+			subjectName: "alice",
+			want:        []rbacv1.Subject{userSubject},
+		},
+		{
+			name:        "group",
+			kind:        "Group",
+			subjects:    []rbacv1.Subject{userSubject, groupSubject},
+			subjectName: "alice",
+			want:        []rbacv1.Subject{groupSubject},
+		},
+		{
+			name:        "service account keeps its subject",
+			kind:        "User",
+			subjects:    []rbacv1.Subject{userSubject, serviceAccountSubject},
+			subjectName: "serviceaccount:default:alice",
+			want:        []rbacv1.Subject{serviceAccountSubject},
+		},
+		{
+			name:        "service account is not a group",
+			kind:        "Group",
+			subjects:    []rbacv1.Subject{serviceAccountSubject},
+			subjectName: "serviceaccount:default:alice",
+		},
+		{
+			name:        "service account without a namespace",
+			kind:        "User",
+			subjects:    []rbacv1.Subject{{Kind: "ServiceAccount", Name: "alice"}},
+			subjectName: "serviceaccount::alice",
+		},
+		{
+			name:        "other user",
+			kind:        "User",
+			subjects:    []rbacv1.Subject{userSubject},
+			subjectName: "bob",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &policyRuleIndex{kind: test.kind}
+			assert.Equal(t, test.want, p.bindingSubjects(test.subjects, test.subjectName))
+		})
+	}
+}
+
+func TestRuleGrantAllows(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+	tests := []struct {
+		name  string
+		rule  rbacv1.PolicyRule
+		verb  string
+		gr    schema.GroupResource
+		rname string
+		want  bool
+	}{
+		{name: "exact", rule: rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}, verb: "get", gr: pods, want: true},
+		{name: "other verb", rule: rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}, verb: "delete", gr: pods},
+		{name: "other group", rule: rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"deployments"}}, verb: "get", gr: deployments},
+		{name: "wildcards", rule: rbacv1.PolicyRule{Verbs: []string{All}, APIGroups: []string{All}, Resources: []string{All}}, verb: "delete", gr: deployments, want: true},
+		{name: "resource name", rule: rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"a"}}, verb: "get", gr: pods, rname: "a", want: true},
+		{name: "other resource name", rule: rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"a"}}, verb: "get", gr: pods, rname: "b"},
+		{name: "resource name without a name", rule: rbacv1.PolicyRule{Verbs: []string{"list"}, APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"a"}}, verb: "list", gr: pods},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, RuleGrant{Rule: test.rule}.Allows(test.verb, test.gr, test.rname))
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -19,7 +20,43 @@ const (
 	HandshakeTimeOut = 10 * time.Second
 )
 
+// Status is the state of the tunnel to the aggregation server
+type Status struct {
+	sync.RWMutex
+	connected bool
+	lastErr   error
+}
+
+func (s *Status) Connected() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.connected
+}
+
+// LastError is the error the tunnel was last closed with
+func (s *Status) LastError() error {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastErr
+}
+
+func (s *Status) set(connected bool, err error) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.connected = connected
+	if err != nil {
+		s.lastErr = err
+	}
+}
+
 func ListenAndServe(ctx context.Context, url string, caCert []byte, token string, handler http.Handler) {
+	listenAndServe(ctx, url, caCert, token, handler, nil)
+}
+
+func listenAndServe(ctx context.Context, url string, caCert []byte, token string, handler http.Handler, status *Status) {
 	dialer := websocket.Dialer{
 		Proxy:            http.ProxyFromEnvironment,
 		HandshakeTimeout: HandshakeTimeOut,
@@ -45,7 +82,8 @@ func ListenAndServe(ctx context.Context, url string, caCert []byte, token string
 	headers.Add("Authorization", "Bearer "+token)
 
 	for {
-		err := serve(ctx, dialer, url, headers, handler)
+		err := serve(ctx, dialer, url, headers, handler, status)
+		status.set(false, err)
 		if err != nil {
 			logrus.Errorf("Failed to dial steve aggregation server: %v", err)
 		}
@@ -57,7 +95,7 @@ func ListenAndServe(ctx context.Context, url string, caCert []byte, token string
 	}
 }
 
-func serve(ctx context.Context, dialer websocket.Dialer, url string, headers http.Header, handler http.Handler) error {
+func serve(ctx context.Context, dialer websocket.Dialer, url string, headers http.Header, handler http.Handler, status *Status) error {
 	url = strings.Replace(url, "http://", "ws://", 1)
 	url = strings.Replace(url, "https://", "wss://", 1)
 	conn, _, err := dialer.DialContext(ctx, url, headers)
@@ -79,6 +117,8 @@ func serve(ctx context.Context, dialer websocket.Dialer, url string, headers htt
 	session := remotedialer.NewClientSessionWithDialer(allowAll, conn, listener.Dial)
 	defer session.Close()
 
+	status.set(true, nil)
+
 	_, err = session.Serve(ctx)
 	return err
 }
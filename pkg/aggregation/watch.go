@@ -10,17 +10,21 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
-func Watch(ctx context.Context, controller v1.SecretController, secretNamespace, secretName string, httpHandler http.Handler) {
+// Watch connects to the aggregation server configured in the secret, the returned status is
+// nil if no secret is configured
+func Watch(ctx context.Context, controller v1.SecretController, secretNamespace, secretName string, httpHandler http.Handler) *Status {
 	if secretNamespace == "" || secretName == "" {
-		return
+		return nil
 	}
 	h := handler{
 		ctx:       ctx,
 		handler:   httpHandler,
 		namespace: secretNamespace,
 		name:      secretName,
+		status:    &Status{},
 	}
 	controller.OnChange(ctx, "aggregation-controller", h.OnSecret)
+	return h.status
 }
 
 type handler struct {
@@ -32,6 +36,7 @@ type handler struct {
 	token  string
 	ctx    context.Context
 	cancel func()
+	status *Status
 }
 
 func (h *handler) OnSecret(key string, secret *corev1.Secret) (*corev1.Secret, error) {
@@ -60,7 +65,7 @@ func (h *handler) OnSecret(key string, secret *corev1.Secret) (*corev1.Secret, e
 	}
 
 	ctx, cancel := context.WithCancel(h.ctx)
-	go ListenAndServe(ctx, url, caCert, token, h.handler)
+	go listenAndServe(ctx, url, caCert, token, h.handler, h.status)
 
 	h.url = url
 	h.caCert = caCert
@@ -165,3 +165,21 @@ func SetPreferredGroup(s *types.APISchema, ver string) {
 	}
 	s.Attributes["preferredGroup"] = ver
 }
+
+// Subresources returns the verbs of each subresource of s keyed by subresource name, such as status or scale
+func Subresources(s *types.APISchema) map[string][]string {
+	result := map[string][]string{}
+	for name, verbs := range convert.ToMapInterface(s.Attributes["subresources"]) {
+		result[name] = convert.ToStringSlice(verbs)
+	}
+	return result
+}
+
+func AddSubresource(s *types.APISchema, name string, verbs []string) {
+	subresources, _ := s.Attributes["subresources"].(map[string]interface{})
+	if subresources == nil {
+		subresources = map[string]interface{}{}
+		setVal(s, "subresources", subresources)
+	}
+	subresources[name] = verbs
+}
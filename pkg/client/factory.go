@@ -7,6 +7,7 @@ import (
 
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/metrics"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
@@ -41,6 +42,9 @@ func (a *addQuery) RoundTrip(req *http.Request) (*http.Response, error) {
 }
 
 func NewFactory(cfg *rest.Config, impersonate bool) (*Factory, error) {
+	cfg = rest.CopyConfig(cfg)
+	cfg.Wrap(metrics.InstrumentTransport)
+
 	clientCfg := rest.CopyConfig(cfg)
 	clientCfg.QPS = 10000
 	clientCfg.Burst = 100
@@ -107,6 +111,12 @@ func (p *Factory) K8sInterface(ctx *types.APIRequest) (kubernetes.Interface, err
 	return kubernetes.NewForConfig(cfg)
 }
 
+// RESTConfig returns the config of the user making the request, for clients such as the
+// SPDY executor that can not be created from an existing client
+func (p *Factory) RESTConfig(ctx *types.APIRequest) (*rest.Config, error) {
+	return setupConfig(ctx, p.clientCfg, p.impersonate)
+}
+
 func (p *Factory) AdminK8sInterface() (kubernetes.Interface, error) {
 	return kubernetes.NewForConfig(p.clientCfg)
 }
@@ -3,10 +3,12 @@ package clustercache
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/metrics"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/wrangler/pkg/merr"
 	"github.com/rancher/wrangler/pkg/summary/client"
@@ -21,6 +23,8 @@ import (
 	"k8s.io/client-go/util/workqueue"
 )
 
+const labelIndex = "labels"
+
 type Handler func(gvr schema2.GroupVersionKind, key string, obj runtime.Object) error
 type ChangeHandler func(gvr schema2.GroupVersionKind, key string, obj, oldObj runtime.Object) error
 
@@ -33,6 +37,23 @@ type ClusterCache interface {
 	OnSchemas(schemas *schema.Collection) error
 }
 
+// Extended is implemented by the cluster cache NewClusterCache returns, its methods are kept
+// out of ClusterCache so other implementations of that interface keep working
+type Extended interface {
+	ClusterCache
+	// SetOptions sets which types are watched and for how long, it must be called before
+	// OnSchemas
+	SetOptions(opts Options)
+	// ListByLabel returns the objects that have the label key, whatever its value
+	ListByLabel(gvk schema2.GroupVersionKind, key string) []interface{}
+	// Unsynced returns the GVKs whose informers are started but not synced yet
+	Unsynced() []schema2.GroupVersionKind
+	// Keep starts the informers of the available GVKs that match and keeps them from being
+	// stopped as idle until ctx is done. Handlers only see the changes of informers that are
+	// running, a consumer that must see every change of a GVK keeps it.
+	Keep(ctx context.Context, matches func(gvk schema2.GroupVersionKind) bool)
+}
+
 type event struct {
 	add    bool
 	gvk    schema2.GroupVersionKind
@@ -46,32 +67,66 @@ type watcher struct {
 	informer cache.SharedIndexInformer
 	gvk      schema2.GroupVersionKind
 	gvr      schema2.GroupVersionResource
+	// lastUsed is the unix nano time the informer was last read from
+	lastUsed int64
+}
+
+func (w *watcher) touch() {
+	atomic.StoreInt64(&w.lastUsed, time.Now().UnixNano())
+}
+
+func (w *watcher) idleSince() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&w.lastUsed))
 }
 
 type clusterCache struct {
 	sync.RWMutex
 
 	ctx           context.Context
+	dynamicClient dynamic.Interface
 	summaryClient client.Interface
-	watchers      map[schema2.GroupVersionKind]*watcher
-	workqueue     workqueue.DelayingInterface
+	summarize     atomic.Value
+	opts          Options
+	stoppingIdle  bool
+	// available are the GVKs that may be watched, in lazy mode they are only watched once needed
+	available map[schema2.GroupVersionKind]schema2.GroupVersionResource
+	watchers  map[schema2.GroupVersionKind]*watcher
+	workqueue workqueue.DelayingInterface
+	events    eventIndex
 
 	addHandlers    cancelCollection
 	removeHandlers cancelCollection
 	changeHandlers cancelCollection
+	keepers        cancelCollection
 }
 
 func NewClusterCache(ctx context.Context, dynamicClient dynamic.Interface) ClusterCache {
 	c := &clusterCache{
 		ctx:           ctx,
-		summaryClient: client.NewForDynamicClient(dynamicClient),
+		dynamicClient: dynamicClient,
+		available:     map[schema2.GroupVersionKind]schema2.GroupVersionResource{},
 		watchers:      map[schema2.GroupVersionKind]*watcher{},
 		workqueue:     workqueue.NewNamedDelayingQueue("cluster-cache"),
 	}
+	c.summaryClient = &summaryClient{
+		client:    dynamicClient,
+		summarize: c.summarized,
+	}
 	go c.start()
 	return c
 }
 
+func (h *clusterCache) SetOptions(opts Options) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.opts = opts
+	if opts.IdleTimeout > 0 && !h.stoppingIdle {
+		h.stoppingIdle = true
+		go h.stopIdle()
+	}
+}
+
 func validSchema(schema *types.APISchema) bool {
 	canList := false
 	canWatch := false
@@ -95,7 +150,7 @@ func (h *clusterCache) addResourceEventHandler(gvk schema2.GroupVersionKind, inf
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			if rObj, ok := obj.(runtime.Object); ok {
-				h.workqueue.Add(event{
+				h.enqueue(event{
 					add: true,
 					obj: rObj,
 					gvk: gvk,
@@ -105,7 +160,7 @@ func (h *clusterCache) addResourceEventHandler(gvk schema2.GroupVersionKind, inf
 		UpdateFunc: func(oldObj, newObj interface{}) {
 			if rObj, ok := newObj.(runtime.Object); ok {
 				if rOldObj, ok := oldObj.(runtime.Object); ok {
-					h.workqueue.Add(event{
+					h.enqueue(event{
 						obj:    rObj,
 						oldObj: rOldObj,
 						gvk:    gvk,
@@ -115,7 +170,7 @@ func (h *clusterCache) addResourceEventHandler(gvk schema2.GroupVersionKind, inf
 		},
 		DeleteFunc: func(obj interface{}) {
 			if rObj, ok := obj.(runtime.Object); ok {
-				h.workqueue.Add(event{
+				h.enqueue(event{
 					obj: rObj,
 					gvk: gvk,
 				})
@@ -124,13 +179,17 @@ func (h *clusterCache) addResourceEventHandler(gvk schema2.GroupVersionKind, inf
 	})
 }
 
+func (h *clusterCache) enqueue(e event) {
+	h.workqueue.Add(e)
+	metrics.SetClusterCacheQueueDepth(h.workqueue.Len())
+}
+
 func (h *clusterCache) OnSchemas(schemas *schema.Collection) error {
 	h.Lock()
-	defer h.Unlock()
 
 	var (
-		gvks   = map[schema2.GroupVersionKind]bool{}
-		toWait []*watcher
+		available = map[schema2.GroupVersionKind]schema2.GroupVersionResource{}
+		toWait    []*watcher
 	)
 
 	for _, id := range schemas.IDs() {
@@ -139,59 +198,204 @@ func (h *clusterCache) OnSchemas(schemas *schema.Collection) error {
 			continue
 		}
 
-		gvr := attributes.GVR(schema)
 		gvk := attributes.GVK(schema)
-		gvks[gvk] = true
-
-		if h.watchers[gvk] != nil {
+		if !h.opts.allowed(gvk) {
 			continue
 		}
-
-		summaryInformer := informer.NewFilteredSummaryInformer(h.summaryClient, gvr, metav1.NamespaceAll, 2*time.Hour,
-			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, nil)
-		ctx, cancel := context.WithCancel(h.ctx)
-		w := &watcher{
-			ctx:      ctx,
-			cancel:   cancel,
-			gvk:      gvk,
-			gvr:      gvr,
-			informer: summaryInformer.Informer(),
-		}
-		h.watchers[gvk] = w
-		toWait = append(toWait, w)
-
-		logrus.Infof("Watching metadata for %s", w.gvk)
-		h.addResourceEventHandler(w.gvk, w.informer)
-		go w.informer.Run(w.ctx.Done())
+		available[gvk] = attributes.GVR(schema)
 	}
+	h.available = available
 
 	for gvk, w := range h.watchers {
-		if !gvks[gvk] {
+		if _, ok := available[gvk]; !ok {
 			logrus.Infof("Stopping metadata watch on %s", gvk)
 			w.cancel()
 			delete(h.watchers, gvk)
+			metrics.DeleteClusterCacheInformer(gvk.String())
 		}
 	}
+	metrics.SetClusterCacheInformers(len(h.watchers))
 
-	for _, w := range toWait {
-		ctx, cancel := context.WithTimeout(w.ctx, 15*time.Minute)
-		if !cache.WaitForCacheSync(ctx.Done(), w.informer.HasSynced) {
-			logrus.Errorf("failed to sync cache for %v", w.gvk)
-			cancel()
-			w.cancel()
-			delete(h.watchers, w.gvk)
+	if !h.opts.Lazy {
+		for gvk, gvr := range available {
+			if h.watchers[gvk] == nil {
+				toWait = append(toWait, h.startWatcher(gvk, gvr))
+			}
 		}
-		cancel()
+	}
+	toWait = append(toWait, h.startKept()...)
+
+	h.Unlock()
+
+	for _, w := range toWait {
+		h.waitForSync(w)
 	}
 
 	return nil
 }
 
-func (h *clusterCache) Get(gvk schema2.GroupVersionKind, namespace, name string) (interface{}, bool, error) {
+// startWatcher starts the informer of a GVK, the write lock must be held
+func (h *clusterCache) startWatcher(gvk schema2.GroupVersionKind, gvr schema2.GroupVersionResource) *watcher {
+	summaryInformer := informer.NewFilteredSummaryInformer(h.summaryClient, gvr, metav1.NamespaceAll, 2*time.Hour,
+		cache.Indexers{
+			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+			labelIndex:           labelIndexer,
+		}, nil)
+	ctx, cancel := context.WithCancel(h.ctx)
+	w := &watcher{
+		ctx:      ctx,
+		cancel:   cancel,
+		gvk:      gvk,
+		gvr:      gvr,
+		informer: summaryInformer.Informer(),
+	}
+	w.touch()
+	h.watchers[gvk] = w
+	metrics.SetClusterCacheInformers(len(h.watchers))
+	metrics.SetClusterCacheInformerSynced(gvk.String(), false)
+
+	logrus.Infof("Watching metadata for %s", w.gvk)
+	h.addResourceEventHandler(w.gvk, w.informer)
+	go w.informer.Run(w.ctx.Done())
+	return w
+}
+
+func (h *clusterCache) Keep(ctx context.Context, matches func(gvk schema2.GroupVersionKind) bool) {
+	h.keepers.Add(ctx, matches)
+
+	h.Lock()
+	toWait := h.startKept()
+	h.Unlock()
+
+	for _, w := range toWait {
+		go h.waitForSync(w)
+	}
+}
+
+// kept returns whether a keeper matches the GVK
+func (h *clusterCache) kept(gvk schema2.GroupVersionKind) bool {
+	for _, keeper := range h.keepers.List() {
+		if keeper.(func(schema2.GroupVersionKind) bool)(gvk) {
+			return true
+		}
+	}
+	return false
+}
+
+// startKept starts the informers of the available GVKs that are kept but not watched, the
+// write lock must be held
+func (h *clusterCache) startKept() (result []*watcher) {
+	for gvk, gvr := range h.available {
+		if h.watchers[gvk] == nil && h.kept(gvk) {
+			result = append(result, h.startWatcher(gvk, gvr))
+		}
+	}
+	return result
+}
+
+func (h *clusterCache) waitForSync(w *watcher) bool {
+	ctx, cancel := context.WithTimeout(w.ctx, 15*time.Minute)
+	defer cancel()
+	if cache.WaitForCacheSync(ctx.Done(), w.informer.HasSynced) {
+		metrics.SetClusterCacheInformerSynced(w.gvk.String(), true)
+		return true
+	}
+
+	logrus.Errorf("failed to sync cache for %v", w.gvk)
+	w.cancel()
+	h.Lock()
+	if h.watchers[w.gvk] == w {
+		delete(h.watchers, w.gvk)
+		metrics.DeleteClusterCacheInformer(w.gvk.String())
+	}
+	metrics.SetClusterCacheInformers(len(h.watchers))
+	h.Unlock()
+	return false
+}
+
+func (h *clusterCache) Unsynced() (result []schema2.GroupVersionKind) {
 	h.RLock()
 	defer h.RUnlock()
 
+	for gvk, w := range h.watchers {
+		if !w.informer.HasSynced() {
+			result = append(result, gvk)
+		}
+	}
+	return result
+}
+
+// watcher returns the watcher of a GVK if it has synced, starting it if it is available
+// but not watched yet. It does not wait for the informer to sync, the objects of a GVK are
+// not found until it has.
+func (h *clusterCache) watcher(gvk schema2.GroupVersionKind) (*watcher, bool) {
+	h.RLock()
 	w, ok := h.watchers[gvk]
+	h.RUnlock()
+
+	if !ok {
+		h.Lock()
+		w, ok = h.watchers[gvk]
+		if !ok {
+			gvr, available := h.available[gvk]
+			if !available {
+				h.Unlock()
+				return nil, false
+			}
+			w = h.startWatcher(gvk, gvr)
+			go h.waitForSync(w)
+		}
+		h.Unlock()
+	}
+
+	w.touch()
+	return w, w.informer.HasSynced()
+}
+
+// stopIdle stops the informers that have not been read from within the idle timeout
+func (h *clusterCache) stopIdle() {
+	h.RLock()
+	idleTimeout := h.opts.IdleTimeout
+	h.RUnlock()
+
+	t := time.NewTicker(idleTimeout / 2)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case now := <-t.C:
+			h.stopIdleWatchers(now)
+		}
+	}
+}
+
+// stopIdleWatchers stops the informers that are idle at now unless they are kept. The
+// handlers are not told about the objects of a stopped informer, they are still in the
+// cluster. The events informer only feeds its own handlers.
+func (h *clusterCache) stopIdleWatchers(now time.Time) {
+	h.RLock()
+	idleTimeout := h.opts.IdleTimeout
+	h.RUnlock()
+	h.stopIdleEvents(now, idleTimeout)
+
+	h.Lock()
+	defer h.Unlock()
+
+	for gvk, w := range h.watchers {
+		if now.Sub(w.idleSince()) > h.opts.IdleTimeout && !h.kept(gvk) {
+			logrus.Infof("Stopping idle metadata watch on %s", gvk)
+			w.cancel()
+			delete(h.watchers, gvk)
+			metrics.DeleteClusterCacheInformer(gvk.String())
+		}
+	}
+	metrics.SetClusterCacheInformers(len(h.watchers))
+}
+
+func (h *clusterCache) Get(gvk schema2.GroupVersionKind, namespace, name string) (interface{}, bool, error) {
+	w, ok := h.watcher(gvk)
 	if !ok {
 		return nil, false, nil
 	}
@@ -207,10 +411,7 @@ func (h *clusterCache) Get(gvk schema2.GroupVersionKind, namespace, name string)
 }
 
 func (h *clusterCache) List(gvk schema2.GroupVersionKind) []interface{} {
-	h.RLock()
-	defer h.RUnlock()
-
-	w, ok := h.watchers[gvk]
+	w, ok := h.watcher(gvk)
 	if !ok {
 		return nil
 	}
@@ -218,6 +419,32 @@ func (h *clusterCache) List(gvk schema2.GroupVersionKind) []interface{} {
 	return w.informer.GetStore().List()
 }
 
+func (h *clusterCache) ListByLabel(gvk schema2.GroupVersionKind, key string) []interface{} {
+	w, ok := h.watcher(gvk)
+	if !ok {
+		return nil
+	}
+
+	objs, err := w.informer.GetIndexer().ByIndex(labelIndex, key)
+	if err != nil {
+		return nil
+	}
+	return objs
+}
+
+// labelIndexer indexes objects by the keys of their labels
+func labelIndexer(obj interface{}) ([]string, error) {
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, nil
+	}
+	result := make([]string, 0, len(meta.GetLabels()))
+	for key := range meta.GetLabels() {
+		result = append(result, key)
+	}
+	return result, nil
+}
+
 func (h *clusterCache) start() {
 	defer h.workqueue.ShutDown()
 	for {
@@ -225,6 +452,7 @@ func (h *clusterCache) start() {
 		if ok {
 			break
 		}
+		metrics.SetClusterCacheQueueDepth(h.workqueue.Len())
 
 		event := eventObj.(event)
 		h.RLock()
@@ -0,0 +1,181 @@
+package clustercache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var (
+	podGVK = schema2.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	podGVR = schema2.GroupVersionResource{Version: "v1", Resource: "pods"}
+)
+
+func testPod(name string) *unstructured.Unstructured {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	pod.SetGroupVersionKind(podGVK)
+	pod.SetNamespace("default")
+	pod.SetName(name)
+	pod.SetResourceVersion("1")
+	pod.SetLabels(map[string]string{"app": name})
+	return pod
+}
+
+// newTestCache returns a cluster cache that may watch pods, the pod list blocks until
+// unblock is closed
+func newTestCache(ctx context.Context, opts Options, unblock chan struct{}, objs ...runtime.Object) *clusterCache {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema2.GroupVersionResource]string{
+		podGVR: "PodList",
+	}, objs...)
+	client.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		<-unblock
+		return false, nil, nil
+	})
+
+	c := NewClusterCache(ctx, client).(*clusterCache)
+	c.SetOptions(opts)
+	c.Lock()
+	c.available[podGVK] = podGVR
+	c.Unlock()
+	return c
+}
+
+func TestOptionsAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		gvk     schema2.GroupVersionKind
+		allowed bool
+	}{
+		{name: "everything", gvk: podGVK, allowed: true},
+		{name: "included core type", opts: Options{Include: []string{"pod"}}, gvk: podGVK, allowed: true},
+		{name: "not included", opts: Options{Include: []string{"secret"}}, gvk: podGVK},
+		{name: "group glob", opts: Options{Include: []string{"*.fleet.cattle.io"}}, gvk: schema2.GroupVersionKind{Group: "fleet.cattle.io", Kind: "Bundle"}, allowed: true},
+		{name: "case insensitive", opts: Options{Include: []string{"Bundle.Fleet.Cattle.IO"}}, gvk: schema2.GroupVersionKind{Group: "fleet.cattle.io", Kind: "Bundle"}, allowed: true},
+		{name: "exclude wins", opts: Options{Include: []string{"*"}, Exclude: []string{"pod"}}, gvk: podGVK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.allowed, test.opts.allowed(test.gvk))
+		})
+	}
+}
+
+func TestWatcherDoesNotWaitForSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	unblock := make(chan struct{})
+	c := newTestCache(ctx, Options{Lazy: true}, unblock, testPod("a"))
+
+	start := time.Now()
+	_, found, err := c.Get(podGVK, "default", "a")
+	assert.NoError(t, err)
+	assert.False(t, found, "the informer has not synced")
+	assert.Nil(t, c.List(podGVK))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second), "reads must not wait for the informer")
+	assert.Equal(t, []schema2.GroupVersionKind{podGVK}, c.Unsynced())
+
+	close(unblock)
+	assert.Eventually(t, func() bool {
+		_, found, _ := c.Get(podGVK, "default", "a")
+		return found
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, c.Unsynced())
+	assert.Len(t, c.ListByLabel(podGVK, "app"), 1)
+	assert.Empty(t, c.ListByLabel(podGVK, "other"))
+}
+
+func TestStopIdleWatchers(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(ctx context.Context, c *clusterCache)
+		idle     time.Duration
+		stopped  bool
+	}{
+		{name: "idle", idle: 2 * time.Minute, stopped: true},
+		{name: "in use", idle: 30 * time.Second},
+		{
+			name:    "handler",
+			idle:    2 * time.Minute,
+			stopped: true,
+			register: func(ctx context.Context, c *clusterCache) {
+				c.OnAdd(ctx, func(schema2.GroupVersionKind, string, runtime.Object) error { return nil })
+			},
+		},
+		{
+			name: "kept",
+			idle: 2 * time.Minute,
+			register: func(ctx context.Context, c *clusterCache) {
+				c.Keep(ctx, func(gvk schema2.GroupVersionKind) bool { return gvk == podGVK })
+			},
+		},
+		{
+			name:    "kept by other",
+			idle:    2 * time.Minute,
+			stopped: true,
+			register: func(ctx context.Context, c *clusterCache) {
+				c.Keep(ctx, func(gvk schema2.GroupVersionKind) bool { return gvk.Kind == "Other" })
+			},
+		},
+		{
+			name:    "no longer kept",
+			idle:    2 * time.Minute,
+			stopped: true,
+			register: func(ctx context.Context, c *clusterCache) {
+				keepCtx, cancel := context.WithCancel(ctx)
+				c.Keep(keepCtx, func(gvk schema2.GroupVersionKind) bool { return gvk == podGVK })
+				cancel()
+				assert.Eventually(t, func() bool {
+					return !c.kept(podGVK)
+				}, 5*time.Second, 10*time.Millisecond)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			unblock := make(chan struct{})
+			close(unblock)
+			c := newTestCache(ctx, Options{Lazy: true}, unblock, testPod("a"))
+			c.opts.IdleTimeout = time.Minute
+			if test.register != nil {
+				test.register(ctx, c)
+			}
+
+			assert.Eventually(t, func() bool {
+				_, found, _ := c.Get(podGVK, "default", "a")
+				return found
+			}, 5*time.Second, 10*time.Millisecond)
+
+			c.stopIdleWatchers(time.Now().Add(test.idle))
+			c.RLock()
+			_, watched := c.watchers[podGVK]
+			c.RUnlock()
+			assert.Equal(t, test.stopped, !watched)
+		})
+	}
+}
+
+func TestKeepStartsWatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	unblock := make(chan struct{})
+	close(unblock)
+	c := newTestCache(ctx, Options{Lazy: true}, unblock, testPod("a"))
+
+	c.Keep(ctx, func(gvk schema2.GroupVersionKind) bool { return gvk == podGVK })
+	c.RLock()
+	w := c.watchers[podGVK]
+	c.RUnlock()
+	if assert.NotNil(t, w, "kept GVKs are watched before they are read") {
+		assert.Eventually(t, w.informer.HasSynced, 5*time.Second, 10*time.Millisecond)
+	}
+}
@@ -0,0 +1,187 @@
+package clustercache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+const involvedObjectIndex = "involvedObject"
+
+var eventsGVR = schema2.GroupVersionResource{
+	Version:  "v1",
+	Resource: "events",
+}
+
+// EventHandler is called with a change to an event involving the object it was added for
+type EventHandler func(eventType watch.EventType, event *unstructured.Unstructured)
+
+// Events is implemented by cluster caches that index events by the object they involve.
+// Objects are matched by group, kind, namespace and name so the events recorded against
+// any version of a kind are found.
+type Events interface {
+	// Events returns the events involving the object, false if the events are not synced yet
+	Events(gk schema2.GroupKind, namespace, name string) ([]*unstructured.Unstructured, bool)
+	// OnEvent calls handler with the changes to the events involving the object until ctx is
+	// done. It is called from the informer so it must not block.
+	OnEvent(ctx context.Context, gk schema2.GroupKind, namespace, name string, handler EventHandler)
+}
+
+// eventIndex is the informer of full event objects, the summary informers only hold
+// metadata so they can not be indexed by involvedObject. It is started once events are
+// asked for and stopped once idle.
+type eventIndex struct {
+	sync.Mutex
+
+	informer cache.SharedIndexInformer
+	cancel   func()
+	lastUsed time.Time
+	nextID   int64
+	handlers map[string]map[int64]EventHandler
+}
+
+func involvedObjectKey(gk schema2.GroupKind, namespace, name string) string {
+	return gk.Group + "/" + gk.Kind + "/" + namespace + "/" + name
+}
+
+func involvedObjectKeyFor(event *unstructured.Unstructured) string {
+	apiVersion, _, _ := unstructured.NestedString(event.Object, "involvedObject", "apiVersion")
+	kind, _, _ := unstructured.NestedString(event.Object, "involvedObject", "kind")
+	namespace, _, _ := unstructured.NestedString(event.Object, "involvedObject", "namespace")
+	name, _, _ := unstructured.NestedString(event.Object, "involvedObject", "name")
+	gv, _ := schema2.ParseGroupVersion(apiVersion)
+	return involvedObjectKey(schema2.GroupKind{Group: gv.Group, Kind: kind}, namespace, name)
+}
+
+func involvedObjectIndexer(obj interface{}) ([]string, error) {
+	event, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, nil
+	}
+	return []string{involvedObjectKeyFor(event)}, nil
+}
+
+// eventInformer returns the events informer, starting it if needed. It does not wait for
+// the informer to sync.
+func (h *clusterCache) eventInformer() cache.SharedIndexInformer {
+	h.events.Lock()
+	defer h.events.Unlock()
+
+	h.events.lastUsed = time.Now()
+	if h.events.informer != nil {
+		return h.events.informer
+	}
+
+	informer := dynamicinformer.NewFilteredDynamicInformer(h.dynamicClient, eventsGVR, metav1.NamespaceAll, 2*time.Hour,
+		cache.Indexers{involvedObjectIndex: involvedObjectIndexer}, nil).Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			h.callEventHandlers(watch.Added, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			h.callEventHandlers(watch.Modified, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			h.callEventHandlers(watch.Deleted, obj)
+		},
+	})
+
+	ctx, cancel := context.WithCancel(h.ctx)
+	h.events.informer = informer
+	h.events.cancel = cancel
+	logrus.Infof("Watching events")
+	go informer.Run(ctx.Done())
+	return informer
+}
+
+func (h *clusterCache) callEventHandlers(eventType watch.EventType, obj interface{}) {
+	event, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	h.events.Lock()
+	var handlers []EventHandler
+	for _, handler := range h.events.handlers[involvedObjectKeyFor(event)] {
+		handlers = append(handlers, handler)
+	}
+	h.events.Unlock()
+
+	for _, handler := range handlers {
+		handler(eventType, event)
+	}
+}
+
+func (h *clusterCache) Events(gk schema2.GroupKind, namespace, name string) ([]*unstructured.Unstructured, bool) {
+	informer := h.eventInformer()
+	if !informer.HasSynced() {
+		return nil, false
+	}
+
+	objs, err := informer.GetIndexer().ByIndex(involvedObjectIndex, involvedObjectKey(gk, namespace, name))
+	if err != nil {
+		return nil, true
+	}
+
+	result := make([]*unstructured.Unstructured, 0, len(objs))
+	for _, obj := range objs {
+		if event, ok := obj.(*unstructured.Unstructured); ok {
+			result = append(result, event)
+		}
+	}
+	return result, true
+}
+
+func (h *clusterCache) OnEvent(ctx context.Context, gk schema2.GroupKind, namespace, name string, handler EventHandler) {
+	h.eventInformer()
+	key := involvedObjectKey(gk, namespace, name)
+
+	h.events.Lock()
+	h.events.nextID++
+	id := h.events.nextID
+	if h.events.handlers == nil {
+		h.events.handlers = map[string]map[int64]EventHandler{}
+	}
+	if h.events.handlers[key] == nil {
+		h.events.handlers[key] = map[int64]EventHandler{}
+	}
+	h.events.handlers[key][id] = handler
+	h.events.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.events.Lock()
+		defer h.events.Unlock()
+		delete(h.events.handlers[key], id)
+		if len(h.events.handlers[key]) == 0 {
+			delete(h.events.handlers, key)
+		}
+		h.events.lastUsed = time.Now()
+	}()
+}
+
+// stopIdleEvents stops the events informer if no handler is added and it has not been read
+// from within the idle timeout
+func (h *clusterCache) stopIdleEvents(now time.Time, idleTimeout time.Duration) {
+	h.events.Lock()
+	defer h.events.Unlock()
+
+	if h.events.informer == nil || len(h.events.handlers) > 0 || now.Sub(h.events.lastUsed) <= idleTimeout {
+		return
+	}
+	logrus.Infof("Stopping idle watch on events")
+	h.events.cancel()
+	h.events.informer = nil
+	h.events.cancel = nil
+}
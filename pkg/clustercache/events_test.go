@@ -0,0 +1,132 @@
+package clustercache
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var deploymentGK = schema2.GroupKind{Group: "apps", Kind: "Deployment"}
+
+func testEvent(name, apiVersion, kind, involvedName string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+		},
+		"involvedObject": map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"namespace":  "default",
+			"name":       involvedName,
+		},
+	}}
+}
+
+func newEventsCache(ctx context.Context, objs ...runtime.Object) (*clusterCache, *fake.FakeDynamicClient) {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema2.GroupVersionResource]string{
+		eventsGVR: "EventList",
+	}, objs...)
+	return NewClusterCache(ctx, client).(*clusterCache), client
+}
+
+func eventNames(events []*unstructured.Unstructured) []string {
+	result := []string{}
+	for _, event := range events {
+		result = append(result, event.GetName())
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		gk   schema2.GroupKind
+		ref  string
+		want []string
+	}{
+		{name: "every version of the kind", gk: deploymentGK, ref: "web", want: []string{"v1", "v1beta2"}},
+		{name: "other name", gk: deploymentGK, ref: "db", want: []string{"db"}},
+		{name: "other group", gk: schema2.GroupKind{Group: "extensions", Kind: "Deployment"}, ref: "web", want: []string{"extensions"}},
+		{name: "no events", gk: schema2.GroupKind{Kind: "Pod"}, ref: "web", want: []string{}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, _ := newEventsCache(ctx,
+		testEvent("v1", "apps/v1", "Deployment", "web"),
+		testEvent("v1beta2", "apps/v1beta2", "Deployment", "web"),
+		testEvent("db", "apps/v1", "Deployment", "db"),
+		testEvent("extensions", "extensions/v1beta1", "Deployment", "web"),
+	)
+	assert.Eventually(t, func() bool {
+		_, synced := c.Events(deploymentGK, "default", "web")
+		return synced
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, synced := c.Events(test.gk, "default", test.ref)
+			assert.True(t, synced)
+			assert.Equal(t, test.want, eventNames(events))
+		})
+	}
+}
+
+func TestOnEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, client := newEventsCache(ctx)
+
+	var (
+		lock sync.Mutex
+		got  []string
+	)
+	handlerCtx, stopHandler := context.WithCancel(ctx)
+	c.OnEvent(handlerCtx, deploymentGK, "default", "web", func(eventType watch.EventType, event *unstructured.Unstructured) {
+		lock.Lock()
+		defer lock.Unlock()
+		got = append(got, string(eventType)+" "+event.GetName())
+	})
+	assert.Eventually(t, c.eventInformer().HasSynced, 5*time.Second, 10*time.Millisecond)
+
+	events := client.Resource(eventsGVR).Namespace("default")
+	for _, event := range []*unstructured.Unstructured{
+		testEvent("other", "apps/v1", "Deployment", "db"),
+		testEvent("mine", "apps/v1", "Deployment", "web"),
+	} {
+		_, err := events.Create(ctx, event, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(got) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"ADDED mine"}, got)
+
+	// the informer is only stopped once the handler is gone
+	c.stopIdleEvents(time.Now().Add(time.Hour), time.Minute)
+	assert.NotNil(t, c.events.informer)
+	stopHandler()
+	assert.Eventually(t, func() bool {
+		c.events.Lock()
+		defer c.events.Unlock()
+		return len(c.events.handlers) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	c.stopIdleEvents(time.Now().Add(time.Hour), time.Minute)
+	assert.Nil(t, c.events.informer)
+}
@@ -0,0 +1,45 @@
+package clustercache
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type Options struct {
+	// Include and Exclude are glob patterns matched against kind.group of a GVK, such as
+	// secret or *.fleet.cattle.io, case insensitive. Everything is included if Include is empty
+	// and Exclude wins over Include.
+	Include []string
+	Exclude []string
+	// Lazy starts the informer of a GVK only once it is first needed instead of for every
+	// schema
+	Lazy bool
+	// IdleTimeout stops informers that have not been read from for that long, they are started
+	// again when needed. Zero never stops them. Informers kept with Extended.Keep are not
+	// stopped, the add, change and remove handlers miss the changes of the others while they
+	// are stopped.
+	IdleTimeout time.Duration
+}
+
+func (o *Options) allowed(gvk schema2.GroupVersionKind) bool {
+	name := strings.ToLower(gvk.Kind)
+	if gvk.Group != "" {
+		name += "." + strings.ToLower(gvk.Group)
+	}
+	if len(o.Include) > 0 && !matchAny(o.Include, name) {
+		return false
+	}
+	return !matchAny(o.Exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package clustercache
+
+import (
+	"context"
+
+	"github.com/rancher/wrangler/pkg/summary"
+	"github.com/rancher/wrangler/pkg/summary/client"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// Summarize returns the summary of an object as it is listed or watched
+type Summarize func(obj runtime.Object) *summary.SummarizedObject
+
+// Summarizing is implemented by cluster caches that let the summaries of the objects they
+// hold be customized
+type Summarizing interface {
+	// SetSummarize sets how objects are summarized, objects already held keep their summary
+	SetSummarize(summarize Summarize)
+	// Resummarize restarts the informers of the kind so every object is summarized again,
+	// the objects are sent to the add handlers again once they are listed
+	Resummarize(gk schema2.GroupKind)
+}
+
+func (h *clusterCache) SetSummarize(summarize Summarize) {
+	h.summarize.Store(summarize)
+}
+
+func (h *clusterCache) Resummarize(gk schema2.GroupKind) {
+	h.Lock()
+	defer h.Unlock()
+
+	for gvk, w := range h.watchers {
+		if gvk.GroupKind() != gk {
+			continue
+		}
+		logrus.Infof("Restarting metadata watch on %s to summarize it again", gvk)
+		w.cancel()
+		go h.waitForSync(h.startWatcher(gvk, w.gvr))
+	}
+}
+
+func (h *clusterCache) summarized(obj runtime.Object) *summary.SummarizedObject {
+	if summarize, ok := h.summarize.Load().(Summarize); ok {
+		return summarize(obj)
+	}
+	return summary.Summarized(obj)
+}
+
+// summaryClient lists and watches objects as their summaries, like the wrangler summary
+// client, but summarizes them with the summarize function of the cluster cache
+type summaryClient struct {
+	client    dynamic.Interface
+	summarize Summarize
+}
+
+type summaryResourceClient struct {
+	client    dynamic.NamespaceableResourceInterface
+	namespace string
+	summarize Summarize
+}
+
+func (c *summaryClient) Resource(resource schema2.GroupVersionResource) client.NamespaceableResourceInterface {
+	return &summaryResourceClient{
+		client:    c.client.Resource(resource),
+		summarize: c.summarize,
+	}
+}
+
+func (c *summaryResourceClient) Namespace(ns string) client.ResourceInterface {
+	ret := *c
+	ret.namespace = ns
+	return &ret
+}
+
+func (c *summaryResourceClient) resource() dynamic.ResourceInterface {
+	if c.namespace == "" {
+		return c.client
+	}
+	return c.client.Namespace(c.namespace)
+}
+
+func (c *summaryResourceClient) List(ctx context.Context, opts metav1.ListOptions) (*summary.SummarizedObjectList, error) {
+	u, err := c.resource().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &summary.SummarizedObjectList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       u.GetKind(),
+			APIVersion: u.GetAPIVersion(),
+		},
+		ListMeta: metav1.ListMeta{
+			ResourceVersion:    u.GetResourceVersion(),
+			Continue:           u.GetContinue(),
+			RemainingItemCount: u.GetRemainingItemCount(),
+		},
+	}
+
+	for i := range u.Items {
+		list.Items = append(list.Items, *c.summarize(&u.Items[i]))
+	}
+
+	return list, nil
+}
+
+func (c *summaryResourceClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	resp, err := c.resource().Watch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	eventChan := make(chan watch.Event)
+	go func() {
+		defer close(eventChan)
+		for event := range resp.ResultChan() {
+			// don't summarize status objects
+			if _, ok := event.Object.(*metav1.Status); !ok {
+				event.Object = c.summarize(event.Object)
+			}
+			eventChan <- event
+		}
+	}()
+
+	return &summaryWatcher{
+		Interface: resp,
+		eventChan: eventChan,
+	}, nil
+}
+
+type summaryWatcher struct {
+	watch.Interface
+	eventChan chan watch.Event
+}
+
+func (w *summaryWatcher) ResultChan() <-chan watch.Event {
+	return w.eventChan
+}
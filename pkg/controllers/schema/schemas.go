@@ -35,6 +35,45 @@ type SchemasHandler interface {
 	OnSchemas(schemas *schema2.Collection) error
 }
 
+// Status is the state of the schema refresh
+type Status struct {
+	sync.RWMutex
+	refreshed   bool
+	lastRefresh time.Time
+	lastErr     error
+}
+
+// Refreshed is whether schemas were refreshed successfully at least once
+func (s *Status) Refreshed() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.refreshed
+}
+
+// LastRefresh is when schemas were last refreshed successfully
+func (s *Status) LastRefresh() time.Time {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastRefresh
+}
+
+// LastError is the error of the last refresh, nil if it succeeded
+func (s *Status) LastError() error {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastErr
+}
+
+func (s *Status) set(err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.lastErr = err
+	if err == nil {
+		s.refreshed = true
+		s.lastRefresh = time.Now()
+	}
+}
+
 type handler struct {
 	sync.Mutex
 
@@ -46,6 +85,7 @@ type handler struct {
 	crd     apiextcontrollerv1.CustomResourceDefinitionClient
 	ssar    authorizationv1client.SelfSubjectAccessReviewInterface
 	handler SchemasHandler
+	status  *Status
 }
 
 func Register(ctx context.Context,
@@ -55,7 +95,7 @@ func Register(ctx context.Context,
 	apiService v1.APIServiceController,
 	ssar authorizationv1client.SelfSubjectAccessReviewInterface,
 	schemasHandler SchemasHandler,
-	schemas *schema2.Collection) {
+	schemas *schema2.Collection) *Status {
 
 	h := &handler{
 		ctx:     ctx,
@@ -65,10 +105,12 @@ func Register(ctx context.Context,
 		handler: schemasHandler,
 		crd:     crd,
 		ssar:    ssar,
+		status:  &Status{},
 	}
 
 	apiService.OnChange(ctx, "schema", h.OnChangeAPIService)
 	crd.OnChange(ctx, "schema", h.OnChangeCRD)
+	return h.status
 }
 
 func (h *handler) OnChangeCRD(key string, crd *apiextv1.CustomResourceDefinition) (*apiextv1.CustomResourceDefinition, error) {
@@ -146,13 +188,16 @@ func (h *handler) getColumns(ctx context.Context, schemas map[string]*types.APIS
 	return eg.Wait()
 }
 
-func (h *handler) refreshAll(ctx context.Context) error {
+func (h *handler) refreshAll(ctx context.Context) (err error) {
 	h.Lock()
 	defer h.Unlock()
 
 	if !h.needToSync() {
 		return nil
 	}
+	defer func() {
+		h.status.set(err)
+	}()
 
 	schemas, err := converter.ToSchemas(h.crd, h.client)
 	if err != nil {
@@ -0,0 +1,93 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Check is the state of one part of the server, the server is ready once every check is
+type Check struct {
+	Name    string `json:"name"`
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+	// Unsynced lists the types that have not synced yet
+	Unsynced []string `json:"unsynced,omitempty"`
+}
+
+type Checker func() Check
+
+type Status struct {
+	Ready  bool    `json:"ready"`
+	Checks []Check `json:"checks"`
+}
+
+type Health struct {
+	sync.RWMutex
+	checkers []Checker
+}
+
+func New() *Health {
+	return &Health{}
+}
+
+// Add adds a check to readiness and the status page
+func (h *Health) Add(checkers ...Checker) {
+	h.Lock()
+	defer h.Unlock()
+	h.checkers = append(h.checkers, checkers...)
+}
+
+func (h *Health) Status() Status {
+	h.RLock()
+	checkers := h.checkers
+	h.RUnlock()
+
+	result := Status{
+		Ready:  true,
+		Checks: make([]Check, 0, len(checkers)),
+	}
+	for _, checker := range checkers {
+		check := checker()
+		result.Ready = result.Ready && check.Ready
+		result.Checks = append(result.Checks, check)
+	}
+	return result
+}
+
+// Healthz responds ok as long as the server is serving
+func (h *Health) Healthz() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Write([]byte("ok"))
+	})
+}
+
+// Readyz responds ok once every check is ready and lists the checks that are not otherwise
+func (h *Health) Readyz() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		status := h.Status()
+		rw.Header().Set("Content-Type", "text/plain")
+		if status.Ready {
+			rw.Write([]byte("ok"))
+			return
+		}
+
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		for _, check := range status.Checks {
+			if !check.Ready {
+				fmt.Fprintf(rw, "%s not ready: %s\n", check.Name, check.Message)
+			}
+		}
+	})
+}
+
+// StatusPage responds with the status of every check as JSON
+func (h *Health) StatusPage() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		status := h.Status()
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(status)
+	})
+}
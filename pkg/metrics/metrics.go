@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "steve"
+
+var (
+	// prometheusMetrics is whether Register was called, nothing is recorded otherwise
+	prometheusMetrics = false
+
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "requests_total",
+		Help:      "Requests to the API by schema, verb and response code",
+	}, []string{"schema", "verb", "code"})
+
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests to the API by schema and verb",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"schema", "verb"})
+
+	upstreamRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests to the Kubernetes API by method and response code",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	watches = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "api",
+		Name:      "watches",
+		Help:      "Active watches by schema",
+	}, []string{"schema"})
+
+	clusterCacheInformers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "cluster_cache",
+		Name:      "informers",
+		Help:      "Informers running in the cluster cache",
+	})
+
+	clusterCacheInformerSynced = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "cluster_cache",
+		Name:      "informer_synced",
+		Help:      "Whether the cluster cache informer of a GVK has synced",
+	}, []string{"gvk"})
+
+	clusterCacheQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "cluster_cache",
+		Name:      "queue_depth",
+		Help:      "Events waiting to be handled by the cluster cache",
+	})
+
+	accessSetCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "access_store",
+		Name:      "cache_requests_total",
+		Help:      "Access set lookups by whether they were a cache hit or miss",
+	}, []string{"result"})
+
+	schemaCacheEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "schema_collection",
+		Name:      "cache_entries",
+		Help:      "Schema sets cached per access set",
+	})
+
+	schemas = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "schema_collection",
+		Name:      "schemas",
+		Help:      "Schemas discovered from the Kubernetes API",
+	})
+)
+
+// Register registers the metrics with the default prometheus registry and starts recording
+// them. It must be called before the server is set up.
+func Register() {
+	if prometheusMetrics {
+		return
+	}
+	prometheusMetrics = true
+	prometheus.MustRegister(
+		apiRequests,
+		apiRequestDuration,
+		upstreamRequestDuration,
+		watches,
+		clusterCacheInformers,
+		clusterCacheInformerSynced,
+		clusterCacheQueueDepth,
+		accessSetCache,
+		schemaCacheEntries,
+		schemas,
+	)
+}
+
+func Enabled() bool {
+	return prometheusMetrics
+}
+
+// Handler serves the registered metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func ObserveAPIRequest(schema, verb string, code int, start time.Time) {
+	if !prometheusMetrics {
+		return
+	}
+	apiRequests.WithLabelValues(schema, verb, strconv.Itoa(code)).Inc()
+	apiRequestDuration.WithLabelValues(schema, verb).Observe(time.Since(start).Seconds())
+}
+
+// InstrumentTransport records the latency of the requests of a transport, it is meant to be
+// passed to rest.Config.Wrap. Requests are only recorded once Register is called, so clients
+// built before that, such as a client factory passed in by an embedder, are recorded too.
+func InstrumentTransport(rt http.RoundTripper) http.RoundTripper {
+	instrumented := promhttp.InstrumentRoundTripperDuration(upstreamRequestDuration, rt)
+	return promhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !prometheusMetrics {
+			return rt.RoundTrip(req)
+		}
+		return instrumented.RoundTrip(req)
+	})
+}
+
+func IncWatches(schema string) {
+	if prometheusMetrics {
+		watches.WithLabelValues(schema).Inc()
+	}
+}
+
+func DecWatches(schema string) {
+	if prometheusMetrics {
+		watches.WithLabelValues(schema).Dec()
+	}
+}
+
+func SetClusterCacheInformers(count int) {
+	if prometheusMetrics {
+		clusterCacheInformers.Set(float64(count))
+	}
+}
+
+func SetClusterCacheInformerSynced(gvk string, synced bool) {
+	if !prometheusMetrics {
+		return
+	}
+	value := 0.0
+	if synced {
+		value = 1
+	}
+	clusterCacheInformerSynced.WithLabelValues(gvk).Set(value)
+}
+
+func DeleteClusterCacheInformer(gvk string) {
+	if prometheusMetrics {
+		clusterCacheInformerSynced.DeleteLabelValues(gvk)
+	}
+}
+
+func SetClusterCacheQueueDepth(depth int) {
+	if prometheusMetrics {
+		clusterCacheQueueDepth.Set(float64(depth))
+	}
+}
+
+func IncAccessSetCache(hit bool) {
+	if !prometheusMetrics {
+		return
+	}
+	if hit {
+		accessSetCache.WithLabelValues("hit").Inc()
+	} else {
+		accessSetCache.WithLabelValues("miss").Inc()
+	}
+}
+
+func SetSchemaCacheEntries(count int) {
+	if prometheusMetrics {
+		schemaCacheEntries.Set(float64(count))
+	}
+}
+
+func SetSchemas(count int) {
+	if prometheusMetrics {
+		schemas.Set(float64(count))
+	}
+}
@@ -0,0 +1,223 @@
+package accessreview
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/rancher/wrangler/pkg/slice"
+	rbacv1 "k8s.io/api/rbac/v1"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// AccessReview is the access of the current user. Reviewing another user or group, with the
+// user and group query parameters, requires being allowed to impersonate them, which admins
+// are.
+type AccessReview struct {
+	ID     string       `json:"id,omitempty"`
+	User   string       `json:"user,omitempty"`
+	Groups []string     `json:"groups,omitempty"`
+	Rules  []ReviewRule `json:"rules"`
+}
+
+// ReviewRule is the verbs allowed on a resource in a namespace, * for every namespace, and
+// the bindings and roles allowing them
+type ReviewRule struct {
+	APIGroup     string   `json:"apiGroup"`
+	Resource     string   `json:"resource"`
+	Namespace    string   `json:"namespace"`
+	ResourceName string   `json:"resourceName,omitempty"`
+	Verbs        []string `json:"verbs"`
+	Sources      []Source `json:"sources"`
+}
+
+type Source struct {
+	Subject rbacv1.Subject        `json:"subject"`
+	Binding accesscontrol.RBACRef `json:"binding"`
+	Role    accesscontrol.RBACRef `json:"role"`
+}
+
+// Register adds the accessreview and whocan schemas
+func Register(schemas *types.APISchemas, explainer accesscontrol.Explainer) {
+	schemas.InternalSchemas.TypeName("accessreview", AccessReview{})
+	schemas.MustImportAndCustomize(AccessReview{}, func(schema *types.APISchema) {
+		schema.Description = "The access of the current user. The user and group query parameters " +
+			"review the access of another user or group and require the impersonate verb on them. " +
+			"The groups of a reviewed user are not looked up, they must be passed explicitly with " +
+			"group, only system:authenticated is added."
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.ResourceMethods = []string{}
+		schema.Store = &reviewStore{
+			explainer: explainer,
+		}
+	})
+
+	schemas.InternalSchemas.TypeName("whocan", WhoCan{})
+	schemas.MustImportAndCustomize(WhoCan{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.ResourceMethods = []string{}
+		schema.Store = &whoCanStore{
+			explainer: explainer,
+		}
+	})
+}
+
+// reviewStore lists the access of the current user. The user and group query parameters
+// review someone else, which requires being allowed to impersonate them.
+type reviewStore struct {
+	empty.Store
+	explainer accesscontrol.Explainer
+}
+
+func (s *reviewStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	subject, err := s.subject(apiOp)
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+
+	q := apiOp.Request.URL.Query()
+	review := AccessReview{
+		ID:     subject.GetName(),
+		User:   subject.GetName(),
+		Groups: subject.GetGroups(),
+		Rules:  toReviewRules(s.explainer.RuleGrants(subject), q.Get("resource"), q.Get("namespace")),
+	}
+
+	return types.APIObjectList{
+		Objects: []types.APIObject{
+			{
+				Type:   "accessreview",
+				ID:     review.ID,
+				Object: review,
+			},
+		},
+	}, nil
+}
+
+func (s *reviewStore) subject(apiOp *types.APIRequest) (user.Info, error) {
+	current, ok := request.UserFrom(apiOp.Context())
+	if !ok {
+		return nil, validation.Unauthorized
+	}
+
+	q := apiOp.Request.URL.Query()
+	name, groups := q.Get("user"), q["group"]
+	if name == "" && len(groups) == 0 {
+		return current, nil
+	}
+
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	if accessSet == nil {
+		return nil, apierror.NewAPIError(validation.PermissionDenied, "can not review the access of others")
+	}
+	if name != "" && !accessSet.Grants("impersonate", runtimeschema.GroupResource{Resource: "users"}, "", name) {
+		return nil, apierror.NewAPIError(validation.PermissionDenied, "can not review the access of user "+name)
+	}
+	for _, group := range groups {
+		if !accessSet.Grants("impersonate", runtimeschema.GroupResource{Resource: "groups"}, "", group) {
+			return nil, apierror.NewAPIError(validation.PermissionDenied, "can not review the access of group "+group)
+		}
+	}
+
+	// like an impersonated request, a reviewed user is authenticated. Its other groups are not
+	// known so they are only the ones passed.
+	if name != "" && name != user.Anonymous && !slice.ContainsString(groups, user.AllAuthenticated) {
+		groups = append(append([]string{}, groups...), user.AllAuthenticated)
+	}
+
+	return &user.DefaultInfo{
+		Name:   name,
+		Groups: groups,
+	}, nil
+}
+
+type ruleKey struct {
+	apiGroup     string
+	resource     string
+	namespace    string
+	resourceName string
+}
+
+// toReviewRules expands the rules of the grants to one rule per resource, namespace and
+// name, optionally only for a resource or namespace
+func toReviewRules(grants []accesscontrol.RuleGrant, resource, namespace string) []ReviewRule {
+	var (
+		verbs   = map[ruleKey]map[string]bool{}
+		sources = map[ruleKey][]Source{}
+		seen    = map[ruleKey]map[Source]bool{}
+	)
+
+	for _, grant := range grants {
+		if namespace != "" && grant.Namespace != accesscontrol.All && grant.Namespace != namespace {
+			continue
+		}
+		names := grant.Rule.ResourceNames
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		source := Source{
+			Subject: grant.Subject,
+			Binding: grant.Binding,
+			Role:    grant.Role,
+		}
+
+		for _, apiGroup := range grant.Rule.APIGroups {
+			for _, r := range grant.Rule.Resources {
+				if resource != "" && r != accesscontrol.All && r != resource {
+					continue
+				}
+				for _, name := range names {
+					key := ruleKey{apiGroup: apiGroup, resource: r, namespace: grant.Namespace, resourceName: name}
+					if verbs[key] == nil {
+						verbs[key] = map[string]bool{}
+						seen[key] = map[Source]bool{}
+					}
+					for _, verb := range grant.Rule.Verbs {
+						verbs[key][verb] = true
+					}
+					if !seen[key][source] {
+						seen[key][source] = true
+						sources[key] = append(sources[key], source)
+					}
+				}
+			}
+		}
+	}
+
+	result := make([]ReviewRule, 0, len(verbs))
+	for key, verbSet := range verbs {
+		rule := ReviewRule{
+			APIGroup:     key.apiGroup,
+			Resource:     key.resource,
+			Namespace:    key.namespace,
+			ResourceName: key.resourceName,
+			Sources:      sources[key],
+		}
+		for verb := range verbSet {
+			rule.Verbs = append(rule.Verbs, verb)
+		}
+		sort.Strings(rule.Verbs)
+		result = append(result, rule)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		left, right := result[i], result[j]
+		if left.APIGroup != right.APIGroup {
+			return left.APIGroup < right.APIGroup
+		}
+		if left.Resource != right.Resource {
+			return left.Resource < right.Resource
+		}
+		if left.Namespace != right.Namespace {
+			return left.Namespace < right.Namespace
+		}
+		return left.ResourceName < right.ResourceName
+	})
+	return result
+}
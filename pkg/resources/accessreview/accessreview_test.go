@@ -0,0 +1,253 @@
+package accessreview
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+var (
+	alice   = rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: "User", Name: "alice"}
+	builder = rbacv1.Subject{Kind: "ServiceAccount", Namespace: "ci", Name: "builder"}
+
+	adminBinding = accesscontrol.RBACRef{Kind: "ClusterRoleBinding", Name: "admin"}
+	adminRole    = accesscontrol.RBACRef{Kind: "ClusterRole", Name: "admin"}
+	ciBinding    = accesscontrol.RBACRef{Kind: "RoleBinding", Namespace: "ci", Name: "builder"}
+	editRole     = accesscontrol.RBACRef{Kind: "ClusterRole", Name: "edit"}
+)
+
+func grant(subject rbacv1.Subject, namespace string, binding, role accesscontrol.RBACRef, rule rbacv1.PolicyRule) accesscontrol.RuleGrant {
+	return accesscontrol.RuleGrant{
+		Subject:   subject,
+		Namespace: namespace,
+		Binding:   binding,
+		Role:      role,
+		Rule:      rule,
+	}
+}
+
+func podRule(verbs ...string) rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{Verbs: verbs, APIGroups: []string{""}, Resources: []string{"pods"}}
+}
+
+func TestToReviewRules(t *testing.T) {
+	var (
+		adminSource = Source{Subject: alice, Binding: adminBinding, Role: adminRole}
+		ciSource    = Source{Subject: builder, Binding: ciBinding, Role: editRole}
+	)
+
+	tests := []struct {
+		name      string
+		grants    []accesscontrol.RuleGrant
+		resource  string
+		namespace string
+		want      []ReviewRule
+	}{
+		{
+			name: "verbs merged",
+			grants: []accesscontrol.RuleGrant{
+				grant(alice, accesscontrol.All, adminBinding, adminRole, podRule("get")),
+				grant(alice, accesscontrol.All, adminBinding, adminRole, podRule("list", "get")),
+			},
+			want: []ReviewRule{
+				{Resource: "pods", Namespace: accesscontrol.All, Verbs: []string{"get", "list"}, Sources: []Source{adminSource}},
+			},
+		},
+		{
+			name: "namespace filter keeps cluster grants",
+			grants: []accesscontrol.RuleGrant{
+				grant(alice, accesscontrol.All, adminBinding, adminRole, podRule("get")),
+				grant(builder, "ci", ciBinding, editRole, podRule("create")),
+			},
+			namespace: "default",
+			want: []ReviewRule{
+				{Resource: "pods", Namespace: accesscontrol.All, Verbs: []string{"get"}, Sources: []Source{adminSource}},
+			},
+		},
+		{
+			name: "resource filter keeps wildcards",
+			grants: []accesscontrol.RuleGrant{
+				grant(alice, accesscontrol.All, adminBinding, adminRole, rbacv1.PolicyRule{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}),
+				grant(builder, "ci", ciBinding, editRole, rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets", "pods"}}),
+			},
+			resource: "pods",
+			want: []ReviewRule{
+				{APIGroup: "", Resource: "pods", Namespace: "ci", Verbs: []string{"get"}, Sources: []Source{ciSource}},
+				{APIGroup: "*", Resource: "*", Namespace: accesscontrol.All, Verbs: []string{"*"}, Sources: []Source{adminSource}},
+			},
+		},
+		{
+			name: "resource names",
+			grants: []accesscontrol.RuleGrant{
+				grant(builder, "ci", ciBinding, editRole, rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"b", "a"}}),
+			},
+			want: []ReviewRule{
+				{Resource: "pods", Namespace: "ci", ResourceName: "a", Verbs: []string{"get"}, Sources: []Source{ciSource}},
+				{Resource: "pods", Namespace: "ci", ResourceName: "b", Verbs: []string{"get"}, Sources: []Source{ciSource}},
+			},
+		},
+		{
+			name: "sources of one rule",
+			grants: []accesscontrol.RuleGrant{
+				grant(alice, "ci", adminBinding, adminRole, podRule("get")),
+				grant(builder, "ci", ciBinding, editRole, podRule("get")),
+			},
+			want: []ReviewRule{
+				{Resource: "pods", Namespace: "ci", Verbs: []string{"get"}, Sources: []Source{adminSource, ciSource}},
+			},
+		},
+		{
+			name: "no grants",
+			want: []ReviewRule{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, toReviewRules(test.grants, test.resource, test.namespace))
+		})
+	}
+}
+
+type fakeExplainer struct {
+	grants []accesscontrol.RuleGrant
+}
+
+func (f *fakeExplainer) RuleGrants(user.Info) []accesscontrol.RuleGrant {
+	return f.grants
+}
+
+func (f *fakeExplainer) SubjectGrants(verb string, gr runtimeschema.GroupResource, namespace, name string) (result []accesscontrol.RuleGrant) {
+	for _, grant := range f.grants {
+		if grant.Allows(verb, gr, name) {
+			result = append(result, grant)
+		}
+	}
+	return result
+}
+
+// bindingAccess is where the user may list bindings
+type bindingAccess struct {
+	gr        runtimeschema.GroupResource
+	namespace string
+}
+
+func TestWhoCan(t *testing.T) {
+	explainer := &fakeExplainer{
+		grants: []accesscontrol.RuleGrant{
+			grant(alice, accesscontrol.All, adminBinding, adminRole, podRule("get")),
+			grant(alice, accesscontrol.All, adminBinding, adminRole, podRule("get", "list")),
+			grant(builder, "ci", ciBinding, editRole, podRule("get")),
+		},
+	}
+
+	tests := []struct {
+		name   string
+		access []bindingAccess
+		want   []WhoCanSubject
+	}{
+		{
+			name:   "all bindings",
+			access: []bindingAccess{{gr: clusterRoleBindingsGR, namespace: accesscontrol.All}, {gr: roleBindingsGR, namespace: accesscontrol.All}},
+			want: []WhoCanSubject{
+				{Subject: builder, Grants: []Grant{{Binding: ciBinding, Role: editRole}}},
+				{Subject: alice, Grants: []Grant{{Binding: adminBinding, Role: adminRole}}},
+			},
+		},
+		{
+			name:   "role bindings in a namespace",
+			access: []bindingAccess{{gr: roleBindingsGR, namespace: "ci"}},
+			want: []WhoCanSubject{
+				{Subject: builder, Grants: []Grant{{Binding: ciBinding, Role: editRole}}},
+			},
+		},
+		{
+			name: "no bindings",
+			want: []WhoCanSubject{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accessSet := &accesscontrol.AccessSet{}
+			for _, access := range test.access {
+				accessSet.Add("list", access.gr, accesscontrol.Access{Namespace: access.namespace, ResourceName: accesscontrol.All})
+			}
+			schemas := types.EmptyAPISchemas()
+			schemas.Attributes = map[string]interface{}{"accessSet": accessSet}
+			apiOp := &types.APIRequest{
+				Request: httptest.NewRequest("GET", "/v1/whocan?verb=get&resource=pods", nil),
+				Schemas: schemas,
+			}
+
+			s := &whoCanStore{explainer: explainer}
+			list, err := s.List(apiOp, nil)
+			if assert.NoError(t, err) && assert.Len(t, list.Objects, 1) {
+				assert.Equal(t, "get::pods::", list.Objects[0].ID)
+				assert.Equal(t, test.want, list.Objects[0].Object.(WhoCan).Subjects)
+			}
+		})
+	}
+}
+
+func TestReviewSubject(t *testing.T) {
+	current := &user.DefaultInfo{Name: "admin", Groups: []string{user.AllAuthenticated}}
+	tests := []struct {
+		name  string
+		query string
+		want  user.Info
+		err   bool
+	}{
+		{name: "current user", want: current},
+		{
+			name:  "user",
+			query: "?user=bob",
+			want:  &user.DefaultInfo{Name: "bob", Groups: []string{user.AllAuthenticated}},
+		},
+		{
+			name:  "user and groups",
+			query: "?user=bob&group=dev",
+			want:  &user.DefaultInfo{Name: "bob", Groups: []string{"dev", user.AllAuthenticated}},
+		},
+		{
+			name:  "authenticated group passed",
+			query: "?user=bob&group=system:authenticated",
+			want:  &user.DefaultInfo{Name: "bob", Groups: []string{user.AllAuthenticated}},
+		},
+		{
+			name:  "group",
+			query: "?group=dev",
+			want:  &user.DefaultInfo{Groups: []string{"dev"}},
+		},
+		{name: "not impersonated", query: "?user=carol", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accessSet := &accesscontrol.AccessSet{}
+			accessSet.Add("impersonate", runtimeschema.GroupResource{Resource: "users"}, accesscontrol.Access{Namespace: accesscontrol.All, ResourceName: "bob"})
+			accessSet.Add("impersonate", runtimeschema.GroupResource{Resource: "groups"}, accesscontrol.Access{Namespace: accesscontrol.All, ResourceName: accesscontrol.All})
+			schemas := types.EmptyAPISchemas()
+			schemas.Attributes = map[string]interface{}{"accessSet": accessSet}
+			req := httptest.NewRequest("GET", "/v1/accessreview"+test.query, nil)
+			apiOp := &types.APIRequest{
+				Request: req.WithContext(request.WithUser(req.Context(), current)),
+				Schemas: schemas,
+			}
+
+			s := &reviewStore{}
+			subject, err := s.subject(apiOp)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.want, subject)
+			}
+		})
+	}
+}
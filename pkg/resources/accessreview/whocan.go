@@ -0,0 +1,133 @@
+package accessreview
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	rbacv1 "k8s.io/api/rbac/v1"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type WhoCan struct {
+	ID        string          `json:"id,omitempty"`
+	Verb      string          `json:"verb"`
+	APIGroup  string          `json:"apiGroup,omitempty"`
+	Resource  string          `json:"resource"`
+	Namespace string          `json:"namespace,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Subjects  []WhoCanSubject `json:"subjects"`
+}
+
+type WhoCanSubject struct {
+	Subject rbacv1.Subject `json:"subject"`
+	Grants  []Grant        `json:"grants"`
+}
+
+type Grant struct {
+	Binding accesscontrol.RBACRef `json:"binding"`
+	Role    accesscontrol.RBACRef `json:"role"`
+}
+
+var (
+	clusterRoleBindingsGR = runtimeschema.GroupResource{
+		Group:    rbacv1.GroupName,
+		Resource: "clusterrolebindings",
+	}
+	roleBindingsGR = runtimeschema.GroupResource{
+		Group:    rbacv1.GroupName,
+		Resource: "rolebindings",
+	}
+)
+
+// whoCanStore lists the subjects allowed a verb on a resource given by the verb, apiGroup,
+// resource, namespace and name query parameters. Only the bindings the user can list are
+// considered.
+type whoCanStore struct {
+	empty.Store
+	explainer accesscontrol.Explainer
+}
+
+func (s *whoCanStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	q := apiOp.Request.URL.Query()
+	whoCan := WhoCan{
+		Verb:      q.Get("verb"),
+		APIGroup:  q.Get("apiGroup"),
+		Resource:  q.Get("resource"),
+		Namespace: q.Get("namespace"),
+		Name:      q.Get("name"),
+		Subjects:  []WhoCanSubject{},
+	}
+	if whoCan.Verb == "" || whoCan.Resource == "" {
+		return types.APIObjectList{}, apierror.NewAPIError(validation.MissingRequired, "verb and resource are required")
+	}
+	whoCan.ID = strings.Join([]string{whoCan.Verb, whoCan.APIGroup, whoCan.Resource, whoCan.Namespace, whoCan.Name}, ":")
+
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	if accessSet == nil {
+		return types.APIObjectList{}, apierror.NewAPIError(validation.PermissionDenied, "can not list bindings")
+	}
+
+	gr := runtimeschema.GroupResource{Group: whoCan.APIGroup, Resource: whoCan.Resource}
+	bySubject := map[rbacv1.Subject]int{}
+	for _, grant := range s.explainer.SubjectGrants(whoCan.Verb, gr, whoCan.Namespace, whoCan.Name) {
+		switch grant.Binding.Kind {
+		case "ClusterRoleBinding":
+			if !accessSet.Grants("list", clusterRoleBindingsGR, "", "") {
+				continue
+			}
+		case "RoleBinding":
+			if !accessSet.Grants("list", roleBindingsGR, grant.Binding.Namespace, "") {
+				continue
+			}
+		}
+
+		i, ok := bySubject[grant.Subject]
+		if !ok {
+			i = len(whoCan.Subjects)
+			bySubject[grant.Subject] = i
+			whoCan.Subjects = append(whoCan.Subjects, WhoCanSubject{
+				Subject: grant.Subject,
+			})
+		}
+		whoCan.Subjects[i].Grants = appendGrant(whoCan.Subjects[i].Grants, Grant{
+			Binding: grant.Binding,
+			Role:    grant.Role,
+		})
+	}
+
+	sort.Slice(whoCan.Subjects, func(i, j int) bool {
+		left, right := whoCan.Subjects[i].Subject, whoCan.Subjects[j].Subject
+		if left.Kind != right.Kind {
+			return left.Kind < right.Kind
+		}
+		if left.Namespace != right.Namespace {
+			return left.Namespace < right.Namespace
+		}
+		return left.Name < right.Name
+	})
+
+	return types.APIObjectList{
+		Objects: []types.APIObject{
+			{
+				Type:   "whocan",
+				ID:     whoCan.ID,
+				Object: whoCan,
+			},
+		},
+	}, nil
+}
+
+// appendGrant adds a grant once, a role with several matching rules is one grant
+func appendGrant(grants []Grant, grant Grant) []Grant {
+	for _, g := range grants {
+		if g == grant {
+			return grants
+		}
+	}
+	return append(grants, grant)
+}
@@ -0,0 +1,215 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/subscribe"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/clustercache"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const eventSchemaID = "event"
+
+var (
+	eventsGR = schema2.GroupResource{
+		Resource: "events",
+	}
+	eventsUpgrader = websocket.Upgrader{
+		HandshakeTimeout:  60 * time.Second,
+		EnableCompression: true,
+	}
+)
+
+// Events serves the events link of an object, the events whose involvedObject is the
+// object are read from the events index of the cluster cache and filtered to the
+// namespaces the user can list events in. A websocket request streams changes to them.
+type Events struct {
+	events clustercache.Events
+}
+
+func (e *Events) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	if err := e.serve(apiOp); err != nil {
+		apiOp.WriteError(err)
+	}
+}
+
+func (e *Events) serve(apiOp *types.APIRequest) error {
+	eventSchema := apiOp.Schemas.LookupSchema(eventSchemaID)
+	if eventSchema == nil {
+		return apierror.NewAPIError(validation.NotFound, "events are not available")
+	}
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	if accessSet == nil {
+		return apierror.NewAPIError(validation.PermissionDenied, "can not list events")
+	}
+
+	// events are matched by group and kind, not version, so events recorded against any
+	// version of the object are found
+	gk := attributes.GVK(apiOp.Schema).GroupKind()
+	canList := func(event *unstructured.Unstructured) bool {
+		return accessSet.Grants("list", eventsGR, event.GetNamespace(), "")
+	}
+
+	eventOp := apiOp.Clone()
+	eventOp.Schema = eventSchema
+	eventOp.Type = eventSchema.ID
+
+	if websocket.IsWebSocketUpgrade(apiOp.Request) {
+		return e.watch(eventOp, gk, apiOp.Name, canList)
+	}
+
+	events, synced := e.events.Events(gk, apiOp.Namespace, apiOp.Name)
+	if !synced {
+		return apierror.NewAPIError(validation.ClusterUnavailable, "events are not synced yet")
+	}
+	var list types.APIObjectList
+	for _, event := range events {
+		if canList(event) {
+			list.Objects = append(list.Objects, toEventObject(event))
+		}
+	}
+	eventOp.WriteResponseList(http.StatusOK, list)
+	return nil
+}
+
+func (e *Events) watch(apiOp *types.APIRequest, gk schema2.GroupKind, name string, canList func(*unstructured.Unstructured) bool) error {
+	ctx, cancel := context.WithCancel(apiOp.Context())
+	defer cancel()
+
+	// the handler is added before the events are read so no change is missed, a change to
+	// an event already sent is sent again
+	var (
+		result  = make(chan types.APIEvent, 100)
+		dropped = make(chan struct{}, 1)
+	)
+	e.events.OnEvent(ctx, gk, apiOp.Namespace, name, func(eventType watch.EventType, event *unstructured.Unstructured) {
+		if !canList(event) {
+			return
+		}
+		select {
+		case result <- types.APIEvent{Name: toEventName(eventType), Object: toEventObject(event)}:
+		default:
+			select {
+			case dropped <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	events, synced := e.events.Events(gk, apiOp.Namespace, name)
+	if !synced {
+		return apierror.NewAPIError(validation.ClusterUnavailable, "events are not synced yet")
+	}
+
+	conn, err := eventsUpgrader.Upgrade(apiOp.Response, apiOp.Request, nil)
+	if err != nil {
+		// the upgrader already responded
+		return nil
+	}
+	defer conn.Close()
+
+	go func() {
+		// the client only ever closes the connection
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, event := range events {
+		if !canList(event) {
+			continue
+		}
+		if err := writeEvent(apiOp, conn, types.APIEvent{
+			Name:   types.CreateAPIEvent,
+			Object: toEventObject(event),
+		}); err != nil {
+			return nil
+		}
+	}
+
+	t := time.NewTicker(30 * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-result:
+			if err := writeEvent(apiOp, conn, event); err != nil {
+				return nil
+			}
+		case <-dropped:
+			// the client has missed events, it is told and has to watch again
+			_ = writeEvent(apiOp, conn, types.APIEvent{
+				Name:  "resource.error",
+				Error: errors.New("events were dropped for a slow watcher, watch again"),
+			})
+			return nil
+		case <-t.C:
+			if err := writeEvent(apiOp, conn, types.APIEvent{Name: "ping"}); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func writeEvent(apiOp *types.APIRequest, conn *websocket.Conn, event types.APIEvent) error {
+	event.ResourceType = eventSchemaID
+	event = subscribe.MarshallObject(apiOp, event)
+	if event.Error != nil {
+		event.Name = "resource.error"
+		event.Data = map[string]interface{}{
+			"error": event.Error.Error(),
+		}
+	}
+
+	messageWriter, err := conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	defer messageWriter.Close()
+
+	return json.NewEncoder(messageWriter).Encode(event)
+}
+
+func toEventName(eventType watch.EventType) string {
+	switch eventType {
+	case watch.Added:
+		return types.CreateAPIEvent
+	case watch.Deleted:
+		return types.RemoveAPIEvent
+	}
+	return types.ChangeAPIEvent
+}
+
+func toEventObject(event *unstructured.Unstructured) types.APIObject {
+	event = event.DeepCopy()
+	return types.APIObject{
+		Type:   eventSchemaID,
+		ID:     event.GetNamespace() + "/" + event.GetName(),
+		Object: event,
+	}
+}
+
+// canListEvents is whether the events link is added to an object in the namespace
+func canListEvents(request *types.APIRequest, meta metav1.Object) bool {
+	accessSet := accesscontrol.AccessSetFromAPIRequest(request)
+	return accessSet != nil && accessSet.Grants("list", eventsGR, meta.GetNamespace(), "")
+}
@@ -1,15 +1,19 @@
 package common
 
 import (
+	"net/http"
 	"strings"
 
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/clustercache"
+	"github.com/rancher/steve/pkg/resources/formatters"
+	"github.com/rancher/steve/pkg/resources/relationshipgraph"
 	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/steve/pkg/stores/partition"
 	"github.com/rancher/steve/pkg/stores/proxy"
 	"github.com/rancher/steve/pkg/summarycache"
-	"github.com/rancher/wrangler/pkg/data"
 	"github.com/rancher/wrangler/pkg/slice"
 	"github.com/rancher/wrangler/pkg/summary"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -18,12 +22,55 @@ import (
 	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// TemplateOption configures the template returned by DefaultTemplate
+type TemplateOption func(*templateOptions)
+
+type templateOptions struct {
+	storeOptions []proxy.StoreOption
+	ccache       clustercache.ClusterCache
+}
+
+// WithContinueTokens signs the continue tokens of lists with tokens
+func WithContinueTokens(tokens *partition.Tokens) TemplateOption {
+	return func(o *templateOptions) {
+		o.storeOptions = append(o.storeOptions, proxy.WithContinueTokens(tokens))
+	}
+}
+
+// WithClusterCache adds the events link to namespaced types if ccache indexes events, see
+// clustercache.Events
+func WithClusterCache(ccache clustercache.ClusterCache) TemplateOption {
+	return func(o *templateOptions) {
+		o.ccache = ccache
+	}
+}
+
 func DefaultTemplate(clientGetter proxy.ClientGetter,
 	summaryCache *summarycache.SummaryCache,
-	asl accesscontrol.AccessSetLookup) schema.Template {
+	asl accesscontrol.AccessSetLookup,
+	opts ...TemplateOption) schema.Template {
+	var o templateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return schema.Template{
-		Store:     proxy.NewProxyStore(clientGetter, summaryCache, asl),
+		Store:     proxy.NewProxyStore(clientGetter, summaryCache, asl, o.storeOptions...),
 		Formatter: formatter(summaryCache),
+		Customize: func(apiSchema *types.APISchema) {
+			proxy.AddSubresourceActions(apiSchema, clientGetter)
+			if apiSchema.LinkHandlers == nil {
+				apiSchema.LinkHandlers = map[string]http.Handler{}
+			}
+			if events, ok := o.ccache.(clustercache.Events); ok && attributes.Namespaced(apiSchema) && apiSchema.ID != eventSchemaID {
+				apiSchema.LinkHandlers["events"] = &Events{
+					events: events,
+				}
+			}
+			apiSchema.LinkHandlers["graph"] = relationshipgraph.NewHandler(summaryCache)
+			// the default template is applied last so projection runs after every other formatter
+			apiSchema.Formatter = types.FormatterChain(apiSchema.Formatter, formatters.ProjectFields)
+		},
 	}
 }
 
@@ -73,17 +120,34 @@ func formatter(summarycache *summarycache.SummaryCache) types.Formatter {
 			resource.Links["update"] = u
 		}
 
-		if unstr, ok := resource.APIObject.Object.(*unstructured.Unstructured); ok {
-			s, rel := summarycache.SummaryAndRelationship(unstr)
-			data.PutValue(unstr.Object, map[string]interface{}{
-				"name":          s.State,
-				"error":         s.Error,
-				"transitioning": s.Transitioning,
-				"message":       strings.Join(s.Message, ":"),
-			}, "metadata", "state")
-			data.PutValue(unstr.Object, rel, "metadata", "relationships")
+		addSubresourceLinks(request, resource, meta)
+
+		// the writer links every link handler
+		if _, ok := resource.Links["events"]; ok && !canListEvents(request, meta) {
+			delete(resource.Links, "events")
+		}
 
+		if unstr, ok := resource.APIObject.Object.(*unstructured.Unstructured); ok {
+			summarycache.AddComputedFields(unstr)
 			summary.NormalizeConditions(unstr)
 		}
 	}
 }
+
+// addSubresourceLinks links the subresources the user can get, such as status and scale
+func addSubresourceLinks(request *types.APIRequest, resource *types.RawResource, meta metav1.Object) {
+	accessSet := accesscontrol.AccessSetFromAPIRequest(request)
+	if accessSet == nil {
+		return
+	}
+	for name, verbs := range attributes.Subresources(resource.Schema) {
+		if _, ok := resource.Links[name]; ok || !slice.ContainsString(verbs, "get") {
+			continue
+		}
+		gr := attributes.GR(resource.Schema)
+		gr.Resource += "/" + name
+		if accessSet.Grants("get", gr, meta.GetNamespace(), meta.GetName()) {
+			resource.Links[name] = request.URLBuilder.Link(resource.Schema, resource.ID, name)
+		}
+	}
+}
@@ -3,19 +3,25 @@ package counts
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/store/empty"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/attributes"
 	"github.com/rancher/steve/pkg/clustercache"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
 	"github.com/rancher/wrangler/pkg/summary"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+const groupByLabelPrefix = "label:"
+
 var (
 	ignore = map[string]bool{
 		"count":   true,
@@ -68,7 +74,12 @@ func (s *Summary) DeepCopy() *Summary {
 type ItemCount struct {
 	Summary    Summary            `json:"summary,omitempty"`
 	Namespaces map[string]Summary `json:"namespaces,omitempty"`
-	Revision   int                `json:"-"`
+	// Groups has a summary for every value of each groupBy key, such as label:team, objects
+	// without a value are not counted in the group
+	Groups map[string]map[string]Summary `json:"groups,omitempty"`
+	// Unsynced is set while the informer of the type has not synced, the counts are partial
+	Unsynced bool `json:"unsynced,omitempty"`
+	Revision int  `json:"-"`
 }
 
 func (i *ItemCount) DeepCopy() *ItemCount {
@@ -80,6 +91,15 @@ func (i *ItemCount) DeepCopy() *ItemCount {
 			r.Namespaces[k] = *v.DeepCopy()
 		}
 	}
+	if r.Groups != nil {
+		r.Groups = map[string]map[string]Summary{}
+		for groupBy, values := range i.Groups {
+			r.Groups[groupBy] = map[string]Summary{}
+			for k, v := range values {
+				r.Groups[groupBy][k] = *v.DeepCopy()
+			}
+		}
+	}
 	return &r
 }
 
@@ -97,12 +117,20 @@ func toAPIObject(c Count) types.APIObject {
 }
 
 func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
-	c := s.getCount(apiOp)
+	groupBy, err := parseGroupBy(apiOp)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	c := s.getCount(apiOp, groupBy)
 	return toAPIObject(c), nil
 }
 
 func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
-	c := s.getCount(apiOp)
+	groupBy, err := parseGroupBy(apiOp)
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+	c := s.getCount(apiOp, groupBy)
 	return types.APIObjectList{
 		Objects: []types.APIObject{
 			toAPIObject(c),
@@ -118,6 +146,11 @@ func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.
 		countLock   sync.Mutex
 	)
 
+	groupBy, err := parseGroupBy(apiOp)
+	if err != nil {
+		return nil, err
+	}
+
 	go func() {
 		<-apiOp.Context().Done()
 		countLock.Lock()
@@ -126,7 +159,7 @@ func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.
 		countLock.Unlock()
 	}()
 
-	counts = s.getCount(apiOp).Counts
+	counts = s.getCount(apiOp, groupBy).Counts
 	for id := range counts {
 		schema := apiOp.Schemas.LookupSchema(id)
 		if schema == nil {
@@ -136,6 +169,30 @@ func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.
 		gvkToSchema[attributes.GVK(schema)] = schema
 	}
 
+	// the informers of the counted types are not stopped while the counts are watched
+	if extended, ok := s.ccache.(clustercache.Extended); ok {
+		extended.Keep(apiOp.Context(), func(gvk schema2.GroupVersionKind) bool {
+			return gvkToSchema[gvk] != nil
+		})
+	}
+
+	// send sends the counts, countLock must be held
+	send := func() {
+		countsCopy := map[string]ItemCount{}
+		for k, v := range counts {
+			countsCopy[k] = *v.DeepCopy()
+		}
+
+		result <- types.APIEvent{
+			Name:         "resource.change",
+			ResourceType: "counts",
+			Object: toAPIObject(Count{
+				ID:     "count",
+				Counts: countsCopy,
+			}),
+		}
+	}
+
 	onChange := func(add bool, gvk schema2.GroupVersionKind, _ string, obj, oldObj runtime.Object) error {
 		countLock.Lock()
 		defer countLock.Unlock()
@@ -153,46 +210,73 @@ func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.
 		if !ok {
 			return nil
 		}
+		groups := groupValues(groupBy, obj)
 
 		itemCount := counts[schema.ID]
-		if revision <= itemCount.Revision {
+		// unsynced types are counted again once they sync
+		if itemCount.Unsynced || revision <= itemCount.Revision {
 			return nil
 		}
 
 		if oldObj != nil {
 			if _, _, _, oldSummary, ok := getInfo(oldObj); ok {
+				oldGroups := groupValues(groupBy, oldObj)
 				if oldSummary.Transitioning == summary.Transitioning &&
 					oldSummary.Error == summary.Error &&
-					simpleState(oldSummary) == simpleState(summary) {
+					simpleState(oldSummary) == simpleState(summary) &&
+					groupsEqual(oldGroups, groups) {
 					return nil
 				}
-				itemCount = removeCounts(itemCount, namespace, oldSummary)
-				itemCount = addCounts(itemCount, namespace, summary)
+				itemCount = removeCounts(itemCount, namespace, oldGroups, oldSummary)
+				itemCount = addCounts(itemCount, namespace, groups, summary)
 			} else {
 				return nil
 			}
 		} else if add {
-			itemCount = addCounts(itemCount, namespace, summary)
+			itemCount = addCounts(itemCount, namespace, groups, summary)
 		} else {
-			itemCount = removeCounts(itemCount, namespace, summary)
+			itemCount = removeCounts(itemCount, namespace, groups, summary)
 		}
 
 		counts[schema.ID] = itemCount
-		countsCopy := map[string]ItemCount{}
-		for k, v := range counts {
-			countsCopy[k] = *v.DeepCopy()
-		}
+		send()
+		return nil
+	}
 
-		result <- types.APIEvent{
-			Name:         "resource.change",
-			ResourceType: "counts",
-			Object: toAPIObject(Count{
-				ID:     "count",
-				Counts: countsCopy,
-			}),
+	// resync counts the types whose informers have synced since they were counted, it
+	// returns whether some are still not synced
+	resync := func() bool {
+		countLock.Lock()
+		defer countLock.Unlock()
+
+		if result == nil {
+			return false
 		}
 
-		return nil
+		var (
+			unsynced = unsyncedGVKs(s.ccache)
+			changed  bool
+			pending  bool
+		)
+		for id, itemCount := range counts {
+			if !itemCount.Unsynced {
+				continue
+			}
+			schema := apiOp.Schemas.LookupSchema(id)
+			if schema == nil {
+				continue
+			}
+			if unsynced[attributes.GVK(schema)] {
+				pending = true
+				continue
+			}
+			counts[id] = s.countSchema(schema, groupBy)
+			changed = true
+		}
+		if changed {
+			send()
+		}
+		return pending
 	}
 
 	s.ccache.OnAdd(apiOp.Context(), func(gvk schema2.GroupVersionKind, key string, obj runtime.Object) error {
@@ -205,6 +289,18 @@ func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.
 		return onChange(false, gvk, key, obj, nil)
 	})
 
+	go func() {
+		t := time.NewTicker(time.Second)
+		defer t.Stop()
+		for resync() {
+			select {
+			case <-apiOp.Context().Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+
 	return buffer(result), nil
 }
 
@@ -252,19 +348,103 @@ func getInfo(obj interface{}) (name string, namespace string, revision int, summ
 	return meta.GetName(), meta.GetNamespace(), revision, summaryResult, true
 }
 
-func removeCounts(itemCount ItemCount, ns string, summary summary.Summary) ItemCount {
+// parseGroupBy returns the groupBy keys of the request, only label:<key> is supported
+func parseGroupBy(apiOp *types.APIRequest) ([]string, error) {
+	var result []string
+	for _, value := range apiOp.Request.URL.Query()["groupBy"] {
+		for _, groupBy := range strings.Split(value, ",") {
+			groupBy = strings.TrimSpace(groupBy)
+			if groupBy == "" {
+				continue
+			}
+			if !strings.HasPrefix(groupBy, groupByLabelPrefix) || groupBy == groupByLabelPrefix {
+				return nil, apierror.NewAPIError(validation.InvalidOption, "unsupported groupBy "+groupBy)
+			}
+			result = append(result, groupBy)
+		}
+	}
+	return result, nil
+}
+
+// listByLabel returns the objects of the GVK that have the label key, every object of the
+// GVK is checked if the cluster cache does not index the label keys
+func listByLabel(ccache clustercache.ClusterCache, gvk schema2.GroupVersionKind, key string) (result []interface{}) {
+	if extended, ok := ccache.(clustercache.Extended); ok {
+		return extended.ListByLabel(gvk, key)
+	}
+	for _, obj := range ccache.List(gvk) {
+		meta, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+		if _, ok := meta.GetLabels()[key]; ok {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// groupValues returns the value of each groupBy key the object has
+func groupValues(groupBy []string, obj interface{}) map[string]string {
+	if len(groupBy) == 0 {
+		return nil
+	}
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	result := map[string]string{}
+	for _, key := range groupBy {
+		if value, ok := meta.GetLabels()[strings.TrimPrefix(key, groupByLabelPrefix)]; ok {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+func groupsEqual(left, right map[string]string) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for k, v := range left {
+		if right[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func removeCounts(itemCount ItemCount, ns string, groups map[string]string, summary summary.Summary) ItemCount {
 	itemCount.Summary = removeSummary(itemCount.Summary, summary)
 	if ns != "" {
 		itemCount.Namespaces[ns] = removeSummary(itemCount.Namespaces[ns], summary)
 	}
+	for groupBy, value := range groups {
+		if itemCount.Groups[groupBy] != nil {
+			itemCount.Groups[groupBy][value] = removeSummary(itemCount.Groups[groupBy][value], summary)
+		}
+	}
 	return itemCount
 }
 
-func addCounts(itemCount ItemCount, ns string, summary summary.Summary) ItemCount {
+func addCounts(itemCount ItemCount, ns string, groups map[string]string, summary summary.Summary) ItemCount {
 	itemCount.Summary = addSummary(itemCount.Summary, summary)
 	if ns != "" {
 		itemCount.Namespaces[ns] = addSummary(itemCount.Namespaces[ns], summary)
 	}
+	return addGroups(itemCount, groups, summary)
+}
+
+func addGroups(itemCount ItemCount, groups map[string]string, summary summary.Summary) ItemCount {
+	for groupBy, value := range groups {
+		if itemCount.Groups == nil {
+			itemCount.Groups = map[string]map[string]Summary{}
+		}
+		if itemCount.Groups[groupBy] == nil {
+			itemCount.Groups[groupBy] = map[string]Summary{}
+		}
+		itemCount.Groups[groupBy][value] = addSummary(itemCount.Groups[groupBy][value], summary)
+	}
 	return itemCount
 }
 
@@ -311,43 +491,88 @@ func simpleState(summary summary.Summary) string {
 	return ""
 }
 
-func (s *Store) getCount(apiOp *types.APIRequest) Count {
-	counts := map[string]ItemCount{}
+// unsyncedGVKs returns the GVKs whose informers have not synced, none if the cluster cache
+// does not tell
+func unsyncedGVKs(ccache clustercache.ClusterCache) map[schema2.GroupVersionKind]bool {
+	result := map[schema2.GroupVersionKind]bool{}
+	if extended, ok := ccache.(clustercache.Extended); ok {
+		for _, gvk := range extended.Unsynced() {
+			result[gvk] = true
+		}
+	}
+	return result
+}
+
+// getCount counts every type the request may list and watch. Types whose informers have not
+// synced are marked Unsynced.
+func (s *Store) getCount(apiOp *types.APIRequest, groupBy []string) Count {
+	var (
+		counts  = map[string]ItemCount{}
+		counted = map[string]schema2.GroupVersionKind{}
+	)
 
 	for _, schema := range s.schemasToWatch(apiOp) {
-		gvk := attributes.GVK(schema)
-		access, _ := attributes.Access(schema).(accesscontrol.AccessListByVerb)
+		counts[schema.ID] = s.countSchema(schema, groupBy)
+		counted[schema.ID] = attributes.GVK(schema)
+	}
 
-		rev := 0
-		itemCount := ItemCount{
-			Namespaces: map[string]Summary{},
+	// checked after listing as listing starts the informers that are not watched yet
+	unsynced := unsyncedGVKs(s.ccache)
+	for id, gvk := range counted {
+		if unsynced[gvk] {
+			itemCount := counts[id]
+			itemCount.Unsynced = true
+			counts[id] = itemCount
 		}
+	}
 
-		all := access.Grants("list", "*", "*")
+	return Count{
+		ID:     "count",
+		Counts: counts,
+	}
+}
 
-		for _, obj := range s.ccache.List(gvk) {
-			name, ns, revision, summary, ok := getInfo(obj)
-			if !ok {
-				continue
-			}
+func (s *Store) countSchema(schema *types.APISchema, groupBy []string) ItemCount {
+	gvk := attributes.GVK(schema)
+	access, _ := attributes.Access(schema).(accesscontrol.AccessListByVerb)
 
-			if !all && !access.Grants("list", ns, name) && !access.Grants("get", ns, name) {
-				continue
-			}
+	rev := 0
+	itemCount := ItemCount{
+		Namespaces: map[string]Summary{},
+	}
 
-			if revision > rev {
-				rev = revision
-			}
+	all := access.Grants("list", "*", "*")
+	canCount := func(ns, name string) bool {
+		return all || access.Grants("list", ns, name) || access.Grants("get", ns, name)
+	}
 
-			itemCount = addCounts(itemCount, ns, summary)
+	for _, obj := range s.ccache.List(gvk) {
+		name, ns, revision, summary, ok := getInfo(obj)
+		if !ok {
+			continue
 		}
 
-		itemCount.Revision = rev
-		counts[schema.ID] = itemCount
+		if !canCount(ns, name) {
+			continue
+		}
+
+		if revision > rev {
+			rev = revision
+		}
+
+		itemCount = addCounts(itemCount, ns, nil, summary)
 	}
 
-	return Count{
-		ID:     "count",
-		Counts: counts,
+	for _, key := range groupBy {
+		for _, obj := range listByLabel(s.ccache, gvk, strings.TrimPrefix(key, groupByLabelPrefix)) {
+			name, ns, _, summary, ok := getInfo(obj)
+			if !ok || !canCount(ns, name) {
+				continue
+			}
+			itemCount = addGroups(itemCount, groupValues([]string{key}, obj), summary)
+		}
 	}
+
+	itemCount.Revision = rev
+	return itemCount
 }
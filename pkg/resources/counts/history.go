@@ -0,0 +1,352 @@
+package counts
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/clustercache"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/rancher/wrangler/pkg/summary"
+	"k8s.io/apimachinery/pkg/runtime"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type CountHistory struct {
+	ID         string        `json:"id,omitempty"`
+	Resolution string        `json:"resolution"`
+	Samples    []CountSample `json:"samples"`
+}
+
+type CountSample struct {
+	Time       string             `json:"time"`
+	Summary    Summary            `json:"summary,omitempty"`
+	Namespaces map[string]Summary `json:"namespaces,omitempty"`
+}
+
+type sample struct {
+	time      time.Time
+	itemCount ItemCount
+}
+
+// ring holds the samples of one type, the oldest sample is overwritten once it is full
+type ring struct {
+	samples []sample
+	next    int
+	full    bool
+}
+
+func (r *ring) add(s sample) {
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *ring) between(start, end time.Time) (result []sample) {
+	if r.full {
+		result = append(result, r.samples[r.next:]...)
+	}
+	result = append(result, r.samples[:r.next]...)
+
+	i, j := 0, len(result)
+	for i < j && result[i].time.Before(start) {
+		i++
+	}
+	for j > i && result[j-1].time.After(end) {
+		j--
+	}
+	return result[i:j]
+}
+
+// countedObject is what the counts of an object were computed from
+type countedObject struct {
+	namespace string
+	summary   summary.Summary
+}
+
+// History keeps the counts of every type the cluster cache watches up to date from its
+// events, samples them at a fixed resolution and keeps the samples for the retention period.
+// Types the cluster cache does not watch are not sampled.
+type History struct {
+	sync.RWMutex
+
+	schemas    *schema.Collection
+	resolution time.Duration
+	retention  time.Duration
+	rings      map[string]*ring
+
+	countsLock sync.Mutex
+	objects    map[schema2.GroupVersionKind]map[string]countedObject
+	counts     map[schema2.GroupVersionKind]ItemCount
+}
+
+// RegisterHistory adds the counthistory schema and starts sampling. Every informer of the
+// cluster cache is kept running so no change is missed.
+func RegisterHistory(ctx context.Context, schemas *types.APISchemas, ccache clustercache.ClusterCache, collection *schema.Collection,
+	resolution, retention time.Duration) {
+	h := &History{
+		schemas:    collection,
+		resolution: resolution,
+		retention:  retention,
+		rings:      map[string]*ring{},
+		objects:    map[schema2.GroupVersionKind]map[string]countedObject{},
+		counts:     map[schema2.GroupVersionKind]ItemCount{},
+	}
+
+	schemas.InternalSchemas.TypeName("counthistory", CountHistory{})
+	schemas.MustImportAndCustomize(CountHistory{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.ResourceMethods = []string{http.MethodGet}
+		schema.Store = &historyStore{
+			history: h,
+		}
+	})
+
+	if extended, ok := ccache.(clustercache.Extended); ok {
+		extended.Keep(ctx, func(schema2.GroupVersionKind) bool {
+			return true
+		})
+	}
+	ccache.OnAdd(ctx, func(gvk schema2.GroupVersionKind, key string, obj runtime.Object) error {
+		h.count(true, gvk, key, obj)
+		return nil
+	})
+	ccache.OnChange(ctx, func(gvk schema2.GroupVersionKind, key string, obj, oldObj runtime.Object) error {
+		h.count(true, gvk, key, obj)
+		return nil
+	})
+	ccache.OnRemove(ctx, func(gvk schema2.GroupVersionKind, key string, obj runtime.Object) error {
+		h.count(false, gvk, key, obj)
+		return nil
+	})
+
+	go h.run(ctx)
+}
+
+// count updates the kept counts of a type with an object that was added or changed, or
+// removed if add is false. An object that is added again replaces its previous counts.
+func (h *History) count(add bool, gvk schema2.GroupVersionKind, key string, obj runtime.Object) {
+	_, ns, _, objSummary, ok := getInfo(obj)
+	if !ok {
+		return
+	}
+
+	h.countsLock.Lock()
+	defer h.countsLock.Unlock()
+
+	objects := h.objects[gvk]
+	if objects == nil {
+		objects = map[string]countedObject{}
+		h.objects[gvk] = objects
+	}
+	itemCount, ok := h.counts[gvk]
+	if !ok {
+		itemCount = ItemCount{
+			Namespaces: map[string]Summary{},
+		}
+	}
+
+	if old, ok := objects[key]; ok {
+		itemCount = removeCounts(itemCount, old.namespace, nil, old.summary)
+		if old.namespace != "" && itemCount.Namespaces[old.namespace].Count <= 0 {
+			delete(itemCount.Namespaces, old.namespace)
+		}
+		delete(objects, key)
+	}
+	if add {
+		// only what the counts are computed from is kept
+		counted := countedObject{
+			namespace: ns,
+			summary: summary.Summary{
+				Error:         objSummary.Error,
+				Transitioning: objSummary.Transitioning,
+			},
+		}
+		itemCount = addCounts(itemCount, counted.namespace, nil, counted.summary)
+		objects[key] = counted
+	}
+	h.counts[gvk] = itemCount
+}
+
+func (h *History) run(ctx context.Context) {
+	t := time.NewTicker(h.resolution)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			h.sample(now)
+		}
+	}
+}
+
+func (h *History) sample(now time.Time) {
+	size := int(h.retention / h.resolution)
+	if size < 1 {
+		size = 1
+	}
+
+	h.countsLock.Lock()
+	counts := make(map[schema2.GroupVersionKind]ItemCount, len(h.counts))
+	for gvk, itemCount := range h.counts {
+		counts[gvk] = *itemCount.DeepCopy()
+	}
+	h.countsLock.Unlock()
+
+	for gvk, itemCount := range counts {
+		id := h.schemas.ByGVK(gvk)
+		if id == "" || ignore[id] {
+			continue
+		}
+
+		h.Lock()
+		r := h.rings[id]
+		if r == nil {
+			r = &ring{
+				samples: make([]sample, size),
+			}
+			h.rings[id] = r
+		}
+		r.add(sample{
+			time:      now,
+			itemCount: itemCount,
+		})
+		h.Unlock()
+	}
+}
+
+// history returns the samples of a type between start and end restricted to the
+// namespaces the user can list the type in
+func (h *History) history(id string, access accesscontrol.AccessListByVerb, start, end time.Time) CountHistory {
+	result := CountHistory{
+		ID:         id,
+		Resolution: h.resolution.String(),
+		Samples:    []CountSample{},
+	}
+
+	h.RLock()
+	r := h.rings[id]
+	var samples []sample
+	if r != nil {
+		samples = r.between(start, end)
+	}
+	h.RUnlock()
+
+	all := access.Grants("list", "*", "*")
+	for _, s := range samples {
+		countSample := CountSample{
+			Time:       s.time.UTC().Format(time.RFC3339),
+			Namespaces: map[string]Summary{},
+		}
+		if all {
+			countSample.Summary = *s.itemCount.Summary.DeepCopy()
+		}
+		for ns, summary := range s.itemCount.Namespaces {
+			if !all && !access.Grants("list", ns, "*") {
+				continue
+			}
+			countSample.Namespaces[ns] = *summary.DeepCopy()
+			if !all {
+				countSample.Summary = mergeSummary(countSample.Summary, summary)
+			}
+		}
+		result.Samples = append(result.Samples, countSample)
+	}
+
+	return result
+}
+
+func mergeSummary(left, right Summary) Summary {
+	left.Count += right.Count
+	left.Error += right.Error
+	left.Transitioning += right.Transitioning
+	for state, count := range right.States {
+		if left.States == nil {
+			left.States = map[string]int{}
+		}
+		left.States[state] += count
+	}
+	return left
+}
+
+type historyStore struct {
+	empty.Store
+	history *History
+}
+
+func (s *historyStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	start, end, err := s.timeRange(apiOp)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+
+	target := apiOp.Schemas.LookupSchema(id)
+	if target == nil {
+		return types.APIObject{}, apierror.NewAPIError(validation.NotFound, "no history for "+id)
+	}
+	if err := apiOp.AccessControl.CanList(apiOp, target); err != nil {
+		return types.APIObject{}, err
+	}
+	access, _ := attributes.Access(target).(accesscontrol.AccessListByVerb)
+	return toHistoryObject(s.history.history(target.ID, access, start, end)), nil
+}
+
+func (s *historyStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	start, end, err := s.timeRange(apiOp)
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+
+	var result types.APIObjectList
+	for _, target := range apiOp.Schemas.Schemas {
+		if ignore[target.ID] || target.Store == nil || apiOp.AccessControl.CanList(apiOp, target) != nil {
+			continue
+		}
+		access, _ := attributes.Access(target).(accesscontrol.AccessListByVerb)
+		history := s.history.history(target.ID, access, start, end)
+		if len(history.Samples) > 0 {
+			result.Objects = append(result.Objects, toHistoryObject(history))
+		}
+	}
+	return result, nil
+}
+
+// timeRange parses the start and end query parameters, both RFC3339 and defaulting to the
+// retention period up to now
+func (s *historyStore) timeRange(apiOp *types.APIRequest) (start time.Time, end time.Time, err error) {
+	q := apiOp.Request.URL.Query()
+	end = time.Now()
+	if value := q.Get("end"); value != "" {
+		end, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return start, end, apierror.NewAPIError(validation.InvalidOption, "invalid end: "+value)
+		}
+	}
+	start = end.Add(-s.history.retention)
+	if value := q.Get("start"); value != "" {
+		start, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return start, end, apierror.NewAPIError(validation.InvalidOption, "invalid start: "+value)
+		}
+	}
+	return start, end, nil
+}
+
+func toHistoryObject(h CountHistory) types.APIObject {
+	return types.APIObject{
+		Type:   "counthistory",
+		ID:     h.ID,
+		Object: h,
+	}
+}
@@ -0,0 +1,95 @@
+package formatters
+
+import (
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/partition/listprocessor"
+	"github.com/rancher/wrangler/pkg/data"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The query parameters read by ProjectFields, subscriptions set them on the request of
+// each watch
+const (
+	FieldsParam               = "fields"
+	ExcludeParam              = "exclude"
+	IncludeManagedFieldsParam = "includeManagedFields"
+)
+
+// ProjectFields trims the resource down to the paths requested with fields= and drops
+// the paths given by exclude=. It must run after all other formatters so computed
+// fields such as metadata.state can be selected. Watch events are formatted with the
+// request of their subscription, so the fields of a subscribe message apply.
+func ProjectFields(request *types.APIRequest, resource *types.RawResource) {
+	if request.Request == nil {
+		return
+	}
+
+	unstr, ok := resource.APIObject.Object.(*unstructured.Unstructured)
+	if !ok || unstr == nil {
+		return
+	}
+
+	q := request.Request.URL.Query()
+	obj := unstr.Object
+
+	if q.Get(IncludeManagedFieldsParam) != "true" {
+		data.RemoveValue(obj, "metadata", "managedFields")
+	}
+
+	if fields := splitParam(q[FieldsParam]); len(fields) > 0 {
+		projected := map[string]interface{}{}
+		for _, field := range fields {
+			copyPath(obj, projected, listprocessor.ParseField(field))
+		}
+		obj = projected
+		unstr.Object = obj
+	}
+
+	for _, field := range splitParam(q[ExcludeParam]) {
+		data.RemoveValue(obj, listprocessor.ParseField(field)...)
+	}
+}
+
+func splitParam(values []string) (result []string) {
+	for _, value := range values {
+		for _, field := range strings.Split(value, ",") {
+			if field != "" {
+				result = append(result, field)
+			}
+		}
+	}
+	return
+}
+
+func copyPath(from, to map[string]interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+
+	val, ok := from[path[0]]
+	if !ok {
+		return
+	}
+
+	if len(path) == 1 {
+		to[path[0]] = val
+		return
+	}
+
+	fromChild, ok := val.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	toChild, ok := to[path[0]].(map[string]interface{})
+	if !ok {
+		toChild = map[string]interface{}{}
+	}
+	copyPath(fromChild, toChild, path[1:])
+	// parents of a missing field are not added
+	if len(toChild) > 0 {
+		to[path[0]] = toChild
+	}
+}
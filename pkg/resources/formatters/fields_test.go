@@ -0,0 +1,147 @@
+package formatters
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func deployment() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "web",
+			"namespace": "default",
+			"labels": map[string]interface{}{
+				"app.kubernetes.io/name": "web",
+				"tier":                   "frontend",
+			},
+			"managedFields": []interface{}{
+				map[string]interface{}{"manager": "kubectl"},
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+		},
+		"status": map[string]interface{}{
+			"readyReplicas": int64(1),
+		},
+	}}
+}
+
+func TestProjectFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]interface{}
+	}{
+		{
+			name:  "managedFields dropped by default",
+			query: "",
+			want: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata": map[string]interface{}{
+					"name":      "web",
+					"namespace": "default",
+					"labels": map[string]interface{}{
+						"app.kubernetes.io/name": "web",
+						"tier":                   "frontend",
+					},
+				},
+				"spec":   map[string]interface{}{"replicas": int64(2)},
+				"status": map[string]interface{}{"readyReplicas": int64(1)},
+			},
+		},
+		{
+			name:  "managedFields included",
+			query: "includeManagedFields=true&fields=metadata.managedFields",
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"managedFields": []interface{}{
+						map[string]interface{}{"manager": "kubectl"},
+					},
+				},
+			},
+		},
+		{
+			name:  "managedFields can not be selected without includeManagedFields",
+			query: "fields=metadata.managedFields,metadata.name",
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "web"},
+			},
+		},
+		{
+			name:  "nested fields",
+			query: "fields=metadata.name,spec.replicas&fields=status.readyReplicas",
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "web"},
+				"spec":     map[string]interface{}{"replicas": int64(2)},
+				"status":   map[string]interface{}{"readyReplicas": int64(1)},
+			},
+		},
+		{
+			name:  "bracketed key",
+			query: "fields=metadata.labels[app.kubernetes.io/name]",
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"app.kubernetes.io/name": "web"},
+				},
+			},
+		},
+		{
+			name:  "missing and non map fields",
+			query: "fields=spec.missing,spec.replicas.value,metadata.name",
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "web"},
+			},
+		},
+		{
+			name:  "exclude",
+			query: "exclude=status,metadata.labels[app.kubernetes.io/name]",
+			want: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata": map[string]interface{}{
+					"name":      "web",
+					"namespace": "default",
+					"labels":    map[string]interface{}{"tier": "frontend"},
+				},
+				"spec": map[string]interface{}{"replicas": int64(2)},
+			},
+		},
+		{
+			name:  "exclude from the selected fields",
+			query: "fields=metadata&exclude=metadata.labels",
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":      "web",
+					"namespace": "default",
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/v1/apps.deployments?"+test.query, nil)
+			obj := deployment()
+			resource := &types.RawResource{APIObject: types.APIObject{Object: obj}}
+
+			ProjectFields(&types.APIRequest{Request: req}, resource)
+			assert.Equal(t, test.want, resource.APIObject.Object.(*unstructured.Unstructured).Object)
+		})
+	}
+}
+
+func TestProjectFieldsIgnoresOtherObjects(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/v1/foo?fields=a", nil)
+	obj := map[string]interface{}{"a": "b", "c": "d"}
+	resource := &types.RawResource{APIObject: types.APIObject{Object: obj}}
+
+	ProjectFields(&types.APIRequest{Request: req}, resource)
+	assert.Equal(t, map[string]interface{}{"a": "b", "c": "d"}, resource.APIObject.Object)
+}
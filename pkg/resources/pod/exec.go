@@ -0,0 +1,351 @@
+package pod
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/client"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const (
+	channelProtocol       = "channel.k8s.io"
+	base64ChannelProtocol = "base64.channel.k8s.io"
+
+	stdinChannel  = 0
+	stdoutChannel = 1
+	stderrChannel = 2
+	errorChannel  = 3
+	resizeChannel = 4
+)
+
+var execUpgrader = websocket.Upgrader{
+	HandshakeTimeout: 60 * time.Second,
+	Subprotocols:     []string{channelProtocol, base64ChannelProtocol},
+}
+
+// Exec bridges a browser websocket to the exec or attach subresource of a pod. Messages use
+// the channel.k8s.io framing, the first byte of a message is the channel and channel 4
+// carries terminal resizes as {"Width":80,"Height":24}.
+type Exec struct {
+	clientFactory *client.Factory
+	subresource   string
+	auditor       Auditor
+}
+
+// AuditEvent records who opened a terminal to which container and for how long
+type AuditEvent struct {
+	User        string    `json:"user"`
+	Subresource string    `json:"subresource"`
+	Namespace   string    `json:"namespace"`
+	Pod         string    `json:"pod"`
+	Container   string    `json:"container,omitempty"`
+	Command     []string  `json:"command,omitempty"`
+	Start       time.Time `json:"start"`
+	Duration    string    `json:"duration"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Auditor receives an AuditEvent when an exec or attach session closes
+type Auditor func(event AuditEvent)
+
+// JSONAuditor writes each AuditEvent to w as one line of JSON
+func JSONAuditor(w io.Writer) Auditor {
+	lock := sync.Mutex{}
+	return func(event AuditEvent) {
+		bytes, err := json.Marshal(event)
+		if err != nil {
+			logrus.Errorf("failed to encode terminal audit event: %v", err)
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		if _, err := w.Write(append(bytes, '\n')); err != nil {
+			logrus.Errorf("failed to write terminal audit event: %v", err)
+		}
+	}
+}
+
+func (e *Exec) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	if err := e.serve(apiOp); err != nil {
+		apiOp.WriteError(err)
+	}
+}
+
+func (e *Exec) serve(apiOp *types.APIRequest) error {
+	if err := checkAccess(apiOp, "create", e.subresource); err != nil {
+		return err
+	}
+	if !websocket.IsWebSocketUpgrade(apiOp.Request) {
+		return apierror.NewAPIError(validation.InvalidOption, e.subresource+" requires a websocket")
+	}
+
+	q := apiOp.Request.URL.Query()
+	var (
+		container = q.Get("container")
+		command   = q["command"]
+		tty       = q.Get("tty") == "true"
+		stdin     = q.Get("stdin") != "false"
+	)
+	if e.subresource == "exec" && len(command) == 0 {
+		return apierror.NewAPIError(validation.MissingRequired, "command is required")
+	}
+
+	cfg, err := e.clientFactory.RESTConfig(apiOp)
+	if err != nil {
+		return err
+	}
+	k8s, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := k8s.CoreV1().RESTClient().Post().
+		Namespace(apiOp.Namespace).
+		Resource("pods").
+		Name(apiOp.Name).
+		SubResource(e.subresource)
+	if e.subresource == "exec" {
+		req.VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin,
+			Stdout:    true,
+			Stderr:    !tty,
+			TTY:       tty,
+		}, scheme.ParameterCodec)
+	} else {
+		req.VersionedParams(&corev1.PodAttachOptions{
+			Container: container,
+			Stdin:     stdin,
+			Stdout:    true,
+			Stderr:    !tty,
+			TTY:       tty,
+		}, scheme.ParameterCodec)
+	}
+
+	transport, spdyUpgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return err
+	}
+	upstream := &closableUpgrader{Upgrader: spdyUpgrader}
+	executor, err := remotecommand.NewSPDYExecutorForTransports(transport, upstream, http.MethodPost, req.URL())
+	if err != nil {
+		return err
+	}
+
+	conn, err := execUpgrader.Upgrade(apiOp.Response, apiOp.Request, nil)
+	if err != nil {
+		// the upgrader already responded
+		return nil
+	}
+	defer conn.Close()
+
+	session := newTerminalSession(conn, stdin)
+	go func() {
+		session.readLoop()
+		// Stream can not be cancelled, closing the SPDY connection once the browser is gone
+		// ends it instead of waiting for the container process to exit
+		upstream.Close()
+	}()
+
+	opts := remotecommand.StreamOptions{
+		Stdout: session.writer(stdoutChannel),
+		Tty:    tty,
+	}
+	if stdin {
+		opts.Stdin = session.stdin
+	}
+	if tty {
+		opts.TerminalSizeQueue = session
+	} else {
+		opts.Stderr = session.writer(stderrChannel)
+	}
+
+	start := time.Now()
+	err = executor.Stream(opts)
+	if err != nil {
+		_, _ = session.writer(errorChannel).Write([]byte(err.Error()))
+	}
+	session.close()
+
+	event := AuditEvent{
+		User:        apiOp.GetUser(),
+		Subresource: e.subresource,
+		Namespace:   apiOp.Namespace,
+		Pod:         apiOp.Name,
+		Container:   container,
+		Command:     command,
+		Start:       start.UTC(),
+		Duration:    time.Since(start).Round(time.Millisecond).String(),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	auditor := e.auditor
+	if auditor == nil {
+		auditor = JSONAuditor(logrus.StandardLogger().Out)
+	}
+	auditor(event)
+	return nil
+}
+
+type terminalSession struct {
+	sync.Mutex
+
+	conn        *websocket.Conn
+	base64      bool
+	hasStdin    bool
+	stdin       *io.PipeReader
+	stdinWriter *io.PipeWriter
+	sizes       chan remotecommand.TerminalSize
+}
+
+func newTerminalSession(conn *websocket.Conn, hasStdin bool) *terminalSession {
+	stdin, stdinWriter := io.Pipe()
+	return &terminalSession{
+		conn:        conn,
+		base64:      conn.Subprotocol() == base64ChannelProtocol,
+		hasStdin:    hasStdin,
+		stdin:       stdin,
+		stdinWriter: stdinWriter,
+		sizes:       make(chan remotecommand.TerminalSize, 1),
+	}
+}
+
+func (t *terminalSession) readLoop() {
+	defer close(t.sizes)
+	defer t.stdinWriter.Close()
+
+	for {
+		_, msg, err := t.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		channel, data, err := t.decode(msg)
+		if err != nil {
+			logrus.Debugf("dropping terminal message: %v", err)
+			continue
+		}
+
+		switch channel {
+		case stdinChannel:
+			// nothing reads the pipe without stdin, writing would block resizes
+			if !t.hasStdin {
+				continue
+			}
+			if _, err := t.stdinWriter.Write(data); err != nil {
+				return
+			}
+		case resizeChannel:
+			var size remotecommand.TerminalSize
+			if err := json.Unmarshal(data, &size); err != nil {
+				logrus.Debugf("invalid terminal resize: %v", err)
+				continue
+			}
+			// only the latest size matters
+			select {
+			case <-t.sizes:
+			default:
+			}
+			t.sizes <- size
+		}
+	}
+}
+
+// Next implements remotecommand.TerminalSizeQueue
+func (t *terminalSession) Next() *remotecommand.TerminalSize {
+	size, ok := <-t.sizes
+	if !ok {
+		return nil
+	}
+	return &size
+}
+
+func (t *terminalSession) decode(msg []byte) (byte, []byte, error) {
+	if len(msg) == 0 {
+		return 0, nil, fmt.Errorf("empty message")
+	}
+	if !t.base64 {
+		return msg[0], msg[1:], nil
+	}
+	data, err := base64.StdEncoding.DecodeString(string(msg[1:]))
+	return msg[0] - '0', data, err
+}
+
+func (t *terminalSession) writer(channel byte) io.Writer {
+	return channelWriter(func(p []byte) (int, error) {
+		t.Lock()
+		defer t.Unlock()
+
+		if t.base64 {
+			msg := string('0'+channel) + base64.StdEncoding.EncodeToString(p)
+			return len(p), t.conn.WriteMessage(websocket.TextMessage, []byte(msg))
+		}
+		return len(p), t.conn.WriteMessage(websocket.BinaryMessage, append([]byte{channel}, p...))
+	})
+}
+
+func (t *terminalSession) close() {
+	t.Lock()
+	defer t.Unlock()
+	_ = t.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+}
+
+// closableUpgrader keeps the SPDY connection it creates so it can be closed, a connection
+// created after Close is closed right away
+type closableUpgrader struct {
+	spdy.Upgrader
+
+	lock   sync.Mutex
+	conn   httpstream.Connection
+	closed bool
+}
+
+func (c *closableUpgrader) NewConnection(resp *http.Response) (httpstream.Connection, error) {
+	conn, err := c.Upgrader.NewConnection(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		_ = conn.Close()
+		return nil, errors.New("terminal closed")
+	}
+	c.conn = conn
+	return conn, nil
+}
+
+func (c *closableUpgrader) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	if c.conn != nil {
+		_ = c.conn.Close()
+	}
+}
+
+type channelWriter func(p []byte) (int, error)
+
+func (c channelWriter) Write(p []byte) (int, error) {
+	return c(p)
+}
@@ -0,0 +1,61 @@
+package pod
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+)
+
+type fakeConnection struct {
+	httpstream.Connection
+	closed bool
+}
+
+func (f *fakeConnection) Close() error {
+	f.closed = true
+	return nil
+}
+
+type fakeUpgrader struct {
+	conns []*fakeConnection
+}
+
+func (f *fakeUpgrader) NewConnection(*http.Response) (httpstream.Connection, error) {
+	conn := &fakeConnection{}
+	f.conns = append(f.conns, conn)
+	return conn, nil
+}
+
+func TestClosableUpgrader(t *testing.T) {
+	tests := []struct {
+		name       string
+		closeFirst bool
+		closeAfter bool
+		wantErr    bool
+		wantClosed bool
+	}{
+		{name: "open"},
+		{name: "closed after the connection", closeAfter: true, wantClosed: true},
+		{name: "closed before the connection", closeFirst: true, wantErr: true, wantClosed: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			upgrader := &fakeUpgrader{}
+			c := &closableUpgrader{Upgrader: upgrader}
+			if test.closeFirst {
+				c.Close()
+			}
+
+			_, err := c.NewConnection(&http.Response{})
+			assert.Equal(t, test.wantErr, err != nil)
+			if test.closeAfter {
+				c.Close()
+			}
+			if assert.Len(t, upgrader.conns, 1) {
+				assert.Equal(t, test.wantClosed, upgrader.conns[0].closed)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package pod
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var upgrader = websocket.Upgrader{
+	HandshakeTimeout:  60 * time.Second,
+	EnableCompression: true,
+}
+
+// Log streams the logs of one or all containers of a pod over a websocket or chunked HTTP.
+// Lines of different containers are prefixed with the container name. Without a container
+// the logs of every container are streamed, including started init and ephemeral containers.
+type Log struct {
+	clientGetter proxy.ClientGetter
+}
+
+func (l *Log) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	if err := l.serve(apiOp); err != nil {
+		apiOp.WriteError(err)
+	}
+}
+
+func (l *Log) serve(apiOp *types.APIRequest) error {
+	if err := checkAccess(apiOp, "get", "log"); err != nil {
+		return err
+	}
+
+	opts, err := logOptions(apiOp.Request.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	client, err := l.clientGetter.K8sInterface(apiOp)
+	if err != nil {
+		return err
+	}
+	pods := client.CoreV1().Pods(apiOp.Namespace)
+
+	containers := []string{opts.Container}
+	if opts.Container == "" {
+		pod, err := pods.Get(apiOp.Context(), apiOp.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		containers = logContainers(pod)
+	}
+
+	ctx, cancel := context.WithCancel(apiOp.Context())
+	defer cancel()
+
+	var streams []io.ReadCloser
+	defer func() {
+		for _, stream := range streams {
+			stream.Close()
+		}
+	}()
+	for _, container := range containers {
+		containerOpts := *opts
+		containerOpts.Container = container
+		stream, err := pods.GetLogs(apiOp.Name, &containerOpts).Stream(ctx)
+		if err != nil {
+			return err
+		}
+		streams = append(streams, stream)
+	}
+
+	write, closeWriter, err := lineWriter(apiOp, cancel)
+	if err != nil {
+		logrus.Debugf("failed to stream logs of %s/%s: %v", apiOp.Namespace, apiOp.Name, err)
+		return nil
+	}
+	defer closeWriter()
+
+	lines := make(chan string)
+	wg := sync.WaitGroup{}
+	for i, stream := range streams {
+		prefix := ""
+		if len(containers) > 1 {
+			prefix = "[" + containers[i] + "] "
+		}
+		wg.Add(1)
+		go func(stream io.Reader) {
+			defer wg.Done()
+			readLines(ctx, stream, prefix, lines)
+		}(stream)
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	for line := range lines {
+		if err := write(line); err != nil {
+			cancel()
+			break
+		}
+	}
+	return nil
+}
+
+// logContainers returns the containers of pod that have logs, init and ephemeral containers
+// only have logs once they started
+func logContainers(pod *corev1.Pod) (result []string) {
+	started := map[string]bool{}
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.EphemeralContainerStatuses} {
+		for _, status := range statuses {
+			if status.State.Running != nil || status.State.Terminated != nil || status.LastTerminationState.Terminated != nil {
+				started[status.Name] = true
+			}
+		}
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		if started[container.Name] {
+			result = append(result, container.Name)
+		}
+	}
+	for _, container := range pod.Spec.Containers {
+		result = append(result, container.Name)
+	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		if started[container.Name] {
+			result = append(result, container.Name)
+		}
+	}
+	return result
+}
+
+func readLines(ctx context.Context, stream io.Reader, prefix string, lines chan<- string) {
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			select {
+			case lines <- prefix + strings.TrimSuffix(line, "\n"):
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// lineWriter upgrades websocket requests and otherwise returns a chunked plain text writer
+func lineWriter(apiOp *types.APIRequest, cancel func()) (func(string) error, func(), error) {
+	if !websocket.IsWebSocketUpgrade(apiOp.Request) {
+		apiOp.Response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		apiOp.Response.WriteHeader(http.StatusOK)
+		flusher, _ := apiOp.Response.(http.Flusher)
+		return func(line string) error {
+			if _, err := io.WriteString(apiOp.Response, line+"\n"); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		}, func() {}, nil
+	}
+
+	c, err := upgrader.Upgrade(apiOp.Response, apiOp.Request, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	go func() {
+		// the client only ever closes the connection
+		defer cancel()
+		for {
+			if _, _, err := c.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(line string) error {
+		return c.WriteMessage(websocket.TextMessage, []byte(line))
+	}
+	closeWriter := func() {
+		_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		c.Close()
+	}
+	return write, closeWriter, nil
+}
+
+func logOptions(q url.Values) (*corev1.PodLogOptions, error) {
+	opts := &corev1.PodLogOptions{
+		Container: q.Get("container"),
+	}
+
+	for name, target := range map[string]*bool{
+		"follow":     &opts.Follow,
+		"timestamps": &opts.Timestamps,
+		"previous":   &opts.Previous,
+	} {
+		if value := q.Get(name); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, apierror.NewAPIError(validation.InvalidOption, "invalid "+name+": "+value)
+			}
+			*target = b
+		}
+	}
+
+	for name, target := range map[string]**int64{
+		"tailLines":    &opts.TailLines,
+		"sinceSeconds": &opts.SinceSeconds,
+	} {
+		if value := q.Get(name); value != "" {
+			i, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || i < 0 {
+				return nil, apierror.NewAPIError(validation.InvalidOption, "invalid "+name+": "+value)
+			}
+			*target = &i
+		}
+	}
+
+	return opts, nil
+}
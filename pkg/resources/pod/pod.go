@@ -0,0 +1,81 @@
+package pod
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/client"
+	"github.com/rancher/steve/pkg/resources/formatters"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func Template(clientFactory *client.Factory) schema.Template {
+	return schema.Template{
+		ID:        "pod",
+		Formatter: types.FormatterChain(formatters.Pod, formatter),
+		Customize: func(apiSchema *types.APISchema) {
+			if apiSchema.LinkHandlers == nil {
+				apiSchema.LinkHandlers = map[string]http.Handler{}
+			}
+			apiSchema.LinkHandlers["log"] = &Log{
+				clientGetter: clientFactory,
+			}
+			for _, subresource := range []string{"exec", "attach"} {
+				apiSchema.LinkHandlers[subresource] = &Exec{
+					clientFactory: clientFactory,
+					subresource:   subresource,
+				}
+			}
+			apiSchema.LinkHandlers["portforward"] = &PortForward{
+				clientFactory: clientFactory,
+			}
+		},
+	}
+}
+
+// AuditTemplate sends the AuditEvents of the exec and attach links of pods to auditor
+// instead of the log, it must be added after Template
+func AuditTemplate(auditor Auditor) schema.Template {
+	return schema.Template{
+		ID: "pod",
+		Customize: func(apiSchema *types.APISchema) {
+			for _, handler := range apiSchema.LinkHandlers {
+				if exec, ok := handler.(*Exec); ok {
+					exec.auditor = auditor
+				}
+			}
+		},
+	}
+}
+
+// formatter drops the links of link handlers the user can not use, the writer adds a link
+// for every link handler
+func formatter(request *types.APIRequest, resource *types.RawResource) {
+	ns, name := resource.APIObject.Data().String("metadata", "namespace"), resource.APIObject.Name()
+	if !can(request, "get", "log", ns, name) {
+		delete(resource.Links, "log")
+	}
+	for _, subresource := range []string{"exec", "attach", "portforward"} {
+		if !can(request, "create", subresource, ns, name) {
+			delete(resource.Links, subresource)
+		}
+	}
+}
+
+func can(apiOp *types.APIRequest, verb, subresource, namespace, name string) bool {
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	return accessSet != nil && accessSet.Grants(verb, schema2.GroupResource{
+		Resource: "pods/" + subresource,
+	}, namespace, name)
+}
+
+func checkAccess(apiOp *types.APIRequest, verb, subresource string) error {
+	if !can(apiOp, verb, subresource, apiOp.Namespace, apiOp.Name) {
+		return apierror.NewAPIError(validation.PermissionDenied, "can not "+verb+" pods/"+subresource)
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package pod
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/client"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// frame types of the port forward tunnel, every websocket message is a binary frame of
+// type (1 byte), stream id (4 bytes big endian) and payload
+const (
+	// frameOpen opens a stream to the port in the payload (2 bytes big endian)
+	frameOpen = 0
+	frameData = 1
+	// frameClose closes a stream from either side
+	frameClose = 2
+	// frameError carries an error message for a stream
+	frameError = 3
+)
+
+// Target resolves the pod a tunnel goes to and maps the ports a client asks for to the
+// ports of that pod
+type Target func(apiOp *types.APIRequest, k8s kubernetes.Interface) (string, func(uint16) (uint16, error), error)
+
+// PortForward tunnels any number of TCP streams to the ports of a pod over one websocket.
+type PortForward struct {
+	clientFactory *client.Factory
+	target        Target
+}
+
+// NewPortForward returns a port forward link handler that tunnels to the pod target picks,
+// such as a ready endpoint pod of a service
+func NewPortForward(clientFactory *client.Factory, target Target) *PortForward {
+	return &PortForward{
+		clientFactory: clientFactory,
+		target:        target,
+	}
+}
+
+func (p *PortForward) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	if err := p.serve(apiOp); err != nil {
+		apiOp.WriteError(err)
+	}
+}
+
+func (p *PortForward) serve(apiOp *types.APIRequest) error {
+	if !websocket.IsWebSocketUpgrade(apiOp.Request) {
+		return apierror.NewAPIError(validation.InvalidOption, "portforward requires a websocket")
+	}
+
+	cfg, err := p.clientFactory.RESTConfig(apiOp)
+	if err != nil {
+		return err
+	}
+	k8s, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	podName, targetPort := apiOp.Name, podPort
+	if p.target != nil {
+		podName, targetPort, err = p.target(apiOp, k8s)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !can(apiOp, "create", "portforward", apiOp.Namespace, podName) {
+		return apierror.NewAPIError(validation.PermissionDenied, "can not create pods/portforward")
+	}
+
+	transport, spdyUpgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return err
+	}
+	req := k8s.CoreV1().RESTClient().Post().
+		Namespace(apiOp.Namespace).
+		Resource("pods").
+		Name(podName).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(spdyUpgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+	streamConn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return err
+	}
+	defer streamConn.Close()
+
+	conn, err := upgrader.Upgrade(apiOp.Response, apiOp.Request, nil)
+	if err != nil {
+		// the upgrader already responded
+		return nil
+	}
+	defer conn.Close()
+
+	t := &tunnel{
+		conn:       conn,
+		streamConn: streamConn,
+		targetPort: targetPort,
+		streams:    map[uint32]*forwardStream{},
+	}
+	t.run()
+	return nil
+}
+
+func podPort(port uint16) (uint16, error) {
+	if port == 0 {
+		return 0, fmt.Errorf("invalid port 0")
+	}
+	return port, nil
+}
+
+// streamBuffer is how many data frames are queued for a stream, a stream that falls
+// further behind is reset so it does not hold up the other streams of the tunnel
+const streamBuffer = 64
+
+type tunnel struct {
+	writeLock sync.Mutex
+	lock      sync.Mutex
+
+	conn       *websocket.Conn
+	streamConn httpstream.Connection
+	targetPort func(uint16) (uint16, error)
+	streams    map[uint32]*forwardStream
+}
+
+// forwardStream writes the data frames of one stream to the pod from its own goroutine
+type forwardStream struct {
+	stream httpstream.Stream
+	data   chan []byte
+	// done is closed once the pod side of the stream ended
+	done chan struct{}
+	// closed is only used by the read loop of the tunnel, which is the only sender on data
+	closed bool
+}
+
+func (f *forwardStream) closeWrites() {
+	if !f.closed {
+		f.closed = true
+		close(f.data)
+	}
+}
+
+func (t *tunnel) writeLoop(id uint32, f *forwardStream) {
+	for {
+		select {
+		case payload, ok := <-f.data:
+			if !ok {
+				// half close once everything queued is written, the pod may still send data
+				f.stream.Close()
+				return
+			}
+			if _, err := f.stream.Write(payload); err != nil {
+				t.write(frameError, id, []byte(err.Error()))
+				f.stream.Reset()
+				return
+			}
+		case <-f.done:
+			return
+		}
+	}
+}
+
+func (t *tunnel) run() {
+	defer t.closeAll()
+
+	for {
+		_, msg, err := t.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if len(msg) < 5 {
+			logrus.Debugf("dropping invalid port forward frame")
+			continue
+		}
+
+		frameType, id, payload := msg[0], binary.BigEndian.Uint32(msg[1:5]), msg[5:]
+		switch frameType {
+		case frameOpen:
+			if err := t.open(id, payload); err != nil {
+				t.write(frameError, id, []byte(err.Error()))
+				t.write(frameClose, id, nil)
+			}
+		case frameData:
+			if f := t.stream(id); f != nil && !f.closed {
+				select {
+				case f.data <- payload:
+				default:
+					t.write(frameError, id, []byte("stream is not read fast enough"))
+					f.stream.Reset()
+				}
+			}
+		case frameClose:
+			if f := t.stream(id); f != nil {
+				f.closeWrites()
+			}
+		}
+	}
+}
+
+func (t *tunnel) open(id uint32, payload []byte) error {
+	if len(payload) != 2 {
+		return fmt.Errorf("invalid port")
+	}
+	port, err := t.targetPort(binary.BigEndian.Uint16(payload))
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	_, exists := t.streams[id]
+	t.lock.Unlock()
+	if exists {
+		return fmt.Errorf("stream %d is already open", id)
+	}
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(int(port)))
+	headers.Set(corev1.PortForwardRequestIDHeader, strconv.Itoa(int(id)))
+	errorStream, err := t.streamConn.CreateStream(headers)
+	if err != nil {
+		return err
+	}
+	// the error stream is only read
+	errorStream.Close()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := t.streamConn.CreateStream(headers)
+	if err != nil {
+		errorStream.Reset()
+		return err
+	}
+
+	f := &forwardStream{
+		stream: dataStream,
+		data:   make(chan []byte, streamBuffer),
+		done:   make(chan struct{}),
+	}
+	t.lock.Lock()
+	t.streams[id] = f
+	t.lock.Unlock()
+	go t.writeLoop(id, f)
+
+	go func() {
+		msg, err := ioutil.ReadAll(errorStream)
+		if err == nil && len(msg) > 0 {
+			t.write(frameError, id, msg)
+		}
+	}()
+
+	go func() {
+		defer func() {
+			t.lock.Lock()
+			delete(t.streams, id)
+			t.lock.Unlock()
+			close(f.done)
+			dataStream.Reset()
+			t.write(frameClose, id, nil)
+		}()
+
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := dataStream.Read(buf)
+			if n > 0 {
+				if err := t.write(frameData, id, buf[:n]); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (t *tunnel) stream(id uint32) *forwardStream {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.streams[id]
+}
+
+func (t *tunnel) write(frameType byte, id uint32, payload []byte) error {
+	msg := make([]byte, 5+len(payload))
+	msg[0] = frameType
+	binary.BigEndian.PutUint32(msg[1:5], id)
+	copy(msg[5:], payload)
+
+	t.writeLock.Lock()
+	defer t.writeLock.Unlock()
+	return t.conn.WriteMessage(websocket.BinaryMessage, msg)
+}
+
+func (t *tunnel) closeAll() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, f := range t.streams {
+		f.closeWrites()
+		f.stream.Reset()
+	}
+}
@@ -0,0 +1,92 @@
+package relationshipgraph
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/schema/converter"
+	"github.com/rancher/steve/pkg/summarycache"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	defaultDepth = 3
+	maxDepth     = 10
+	maxNodes     = 500
+)
+
+type RelationshipGraph struct {
+	Root      string                   `json:"root"`
+	Depth     int                      `json:"depth"`
+	Nodes     []summarycache.GraphNode `json:"nodes"`
+	Edges     []summarycache.GraphEdge `json:"edges"`
+	Truncated bool                     `json:"truncated,omitempty"`
+}
+
+func Register(schemas *types.APISchemas) {
+	schemas.InternalSchemas.TypeName("relationshipgraph", RelationshipGraph{})
+	schemas.MustImportAndCustomize(RelationshipGraph{}, nil)
+}
+
+// Handler serves the graph link of an object, the objects it is transitively related to
+// that the user can get
+type Handler struct {
+	summaryCache *summarycache.SummaryCache
+}
+
+func NewHandler(summaryCache *summarycache.SummaryCache) *Handler {
+	return &Handler{
+		summaryCache: summaryCache,
+	}
+}
+
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	if err := h.serve(apiOp); err != nil {
+		apiOp.WriteError(err)
+	}
+}
+
+func (h *Handler) serve(apiOp *types.APIRequest) error {
+	depth := defaultDepth
+	if value := apiOp.Request.URL.Query().Get("depth"); value != "" {
+		d, err := strconv.Atoi(value)
+		if err != nil || d < 0 || d > maxDepth {
+			return apierror.NewAPIError(validation.InvalidOption, "depth must be between 0 and "+strconv.Itoa(maxDepth))
+		}
+		depth = d
+	}
+
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	if accessSet == nil {
+		return apierror.NewAPIError(validation.PermissionDenied, "can not get relationships")
+	}
+
+	canGet := func(gvk runtimeschema.GroupVersionKind, namespace, name string) bool {
+		schema := apiOp.Schemas.LookupSchema(converter.GVKToSchemaID(gvk))
+		return schema != nil && accessSet.Grants("get", attributes.GR(schema), namespace, name)
+	}
+
+	graph := h.summaryCache.Graph(attributes.GVK(apiOp.Schema), apiOp.Namespace, apiOp.Name, depth, maxNodes, canGet)
+	root := apiOp.Name
+	if apiOp.Namespace != "" {
+		root = apiOp.Namespace + "/" + apiOp.Name
+	}
+
+	apiOp.WriteResponse(http.StatusOK, types.APIObject{
+		Type: "relationshipgraph",
+		Object: RelationshipGraph{
+			Root:      root,
+			Depth:     depth,
+			Nodes:     graph.Nodes,
+			Edges:     graph.Edges,
+			Truncated: graph.Truncated,
+		},
+	})
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 
 	"github.com/rancher/apiserver/pkg/store/apiroot"
-	"github.com/rancher/apiserver/pkg/subscribe"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/client"
@@ -14,6 +13,10 @@ import (
 	"github.com/rancher/steve/pkg/resources/common"
 	"github.com/rancher/steve/pkg/resources/counts"
 	"github.com/rancher/steve/pkg/resources/formatters"
+	"github.com/rancher/steve/pkg/resources/pod"
+	"github.com/rancher/steve/pkg/resources/relationshipgraph"
+	"github.com/rancher/steve/pkg/resources/service"
+	"github.com/rancher/steve/pkg/resources/subscribe"
 	"github.com/rancher/steve/pkg/resources/userpreferences"
 	"github.com/rancher/steve/pkg/schema"
 	steveschema "github.com/rancher/steve/pkg/schema"
@@ -29,6 +32,7 @@ func DefaultSchemas(ctx context.Context, baseSchema *types.APISchemas, ccache cl
 	apiroot.Register(baseSchema, []string{"v1"}, "proxy:/apis")
 	cluster.Register(ctx, baseSchema, cg, schemaFactory)
 	userpreferences.Register(baseSchema)
+	relationshipgraph.Register(baseSchema)
 	return nil
 }
 
@@ -36,9 +40,10 @@ func DefaultSchemaTemplates(cf *client.Factory,
 	baseSchemas *types.APISchemas,
 	summaryCache *summarycache.SummaryCache,
 	lookup accesscontrol.AccessSetLookup,
-	discovery discovery.DiscoveryInterface) []schema.Template {
+	discovery discovery.DiscoveryInterface,
+	opts ...common.TemplateOption) []schema.Template {
 	return []schema.Template{
-		common.DefaultTemplate(cf, summaryCache, lookup),
+		common.DefaultTemplate(cf, summaryCache, lookup, opts...),
 		apigroups.Template(discovery),
 		{
 			ID:        "configmap",
@@ -48,10 +53,8 @@ func DefaultSchemaTemplates(cf *client.Factory,
 			ID:        "secret",
 			Formatter: formatters.DropHelmData,
 		},
-		{
-			ID:        "pod",
-			Formatter: formatters.Pod,
-		},
+		pod.Template(cf),
+		service.Template(cf),
 		{
 			ID: "management.cattle.io.cluster",
 			Customize: func(apiSchema *types.APISchema) {
@@ -79,7 +79,7 @@ func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.
 			logrus.Errorf("failed to generate schemas for notify user %v: %v", user, err)
 			return
 		}
-		for range s.userChangeNotify(apiOp.Context(), user) {
+		for range accesscontrol.WatchAccess(apiOp.Context(), s.asl, user, 2*time.Second) {
 			schemas = s.sendSchemas(result, apiOp, user, schemas)
 		}
 	}()
@@ -118,29 +118,6 @@ func (s *Store) sendSchemas(result chan types.APIEvent, apiOp *types.APIRequest,
 	return schemas
 }
 
-func (s *Store) userChangeNotify(ctx context.Context, user user.Info) chan interface{} {
-	as := s.asl.AccessFor(user)
-	result := make(chan interface{})
-	go func() {
-		defer close(result)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(2 * time.Second):
-			}
-
-			newAS := s.asl.AccessFor(user)
-			if newAS.ID != as.ID {
-				result <- struct{}{}
-				as = newAS
-			}
-		}
-	}()
-
-	return result
-}
-
 func schemaChangeNotifier(ctx context.Context, factory schema.Factory) func(ctx context.Context) (chan interface{}, error) {
 	notify := make(chan interface{})
 	bcast := &broadcast.Broadcaster{}
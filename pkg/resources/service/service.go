@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/client"
+	"github.com/rancher/steve/pkg/resources/pod"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Template adds the portforward link to services, the tunnel goes to a ready endpoint pod
+// and service ports are mapped to the target ports of that pod
+func Template(clientFactory *client.Factory) schema.Template {
+	return schema.Template{
+		ID:        "service",
+		Formatter: formatter,
+		Customize: func(apiSchema *types.APISchema) {
+			if apiSchema.LinkHandlers == nil {
+				apiSchema.LinkHandlers = map[string]http.Handler{}
+			}
+			apiSchema.LinkHandlers["portforward"] = pod.NewPortForward(clientFactory, serviceTarget)
+		},
+	}
+}
+
+// formatter drops the portforward link if the user can not port forward to any pod of the
+// namespace, the service is resolved to a pod when the tunnel is opened so pods/portforward
+// of that pod is checked then
+func formatter(request *types.APIRequest, resource *types.RawResource) {
+	accessSet := accesscontrol.AccessSetFromAPIRequest(request)
+	namespace := resource.APIObject.Data().String("metadata", "namespace")
+	if accessSet == nil || !accessSet.Grants("create", schema2.GroupResource{Resource: "pods/portforward"}, namespace, "") {
+		delete(resource.Links, "portforward")
+	}
+}
+
+// serviceTarget picks a ready pod behind the service and maps service ports to its ports
+func serviceTarget(apiOp *types.APIRequest, k8s kubernetes.Interface) (string, func(uint16) (uint16, error), error) {
+	svc, err := k8s.CoreV1().Services(apiOp.Namespace).Get(apiOp.Context(), apiOp.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+	endpoints, err := k8s.CoreV1().Endpoints(apiOp.Namespace).Get(apiOp.Context(), apiOp.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			if address.TargetRef == nil || address.TargetRef.Kind != "Pod" {
+				continue
+			}
+			subset := subset
+			return address.TargetRef.Name, func(port uint16) (uint16, error) {
+				return endpointPort(svc, subset, port)
+			}, nil
+		}
+	}
+
+	return "", nil, apierror.NewAPIError(validation.NotFound, "service "+apiOp.Name+" has no ready pods")
+}
+
+func endpointPort(svc *corev1.Service, subset corev1.EndpointSubset, port uint16) (uint16, error) {
+	for _, servicePort := range svc.Spec.Ports {
+		if servicePort.Port != int32(port) {
+			continue
+		}
+		for _, endpointPort := range subset.Ports {
+			if endpointPort.Name == servicePort.Name {
+				return uint16(endpointPort.Port), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("service %s has no port %d", svc.Name, port)
+}
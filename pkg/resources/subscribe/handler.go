@@ -0,0 +1,96 @@
+package subscribe
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/subscribe"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+)
+
+var upgrader = websocket.Upgrader{
+	HandshakeTimeout:  60 * time.Second,
+	EnableCompression: true,
+}
+
+// Subscribe is the message a client sends to start or stop a watch. Fields, Exclude and
+// IncludeManagedFields apply to the events of that watch only, like the fields, exclude
+// and includeManagedFields query parameters of a list.
+type Subscribe struct {
+	Stop                 bool     `json:"stop,omitempty"`
+	ResourceType         string   `json:"resourceType,omitempty"`
+	ResourceVersion      string   `json:"resourceVersion,omitempty"`
+	Namespace            string   `json:"namespace,omitempty"`
+	ID                   string   `json:"id,omitempty"`
+	Selector             string   `json:"selector,omitempty"`
+	Fields               []string `json:"fields,omitempty"`
+	Exclude              []string `json:"exclude,omitempty"`
+	IncludeManagedFields bool     `json:"includeManagedFields,omitempty"`
+}
+
+func (s *Subscribe) key() string {
+	return s.ResourceType + "/" + s.Namespace + "/" + s.ID + "/" + s.Selector
+}
+
+func Handler(apiOp *types.APIRequest) (types.APIObjectList, error) {
+	err := handler(apiOp)
+	if err != nil {
+		logrus.Errorf("Error during subscribe %v", err)
+	}
+	return types.APIObjectList{}, validation.ErrComplete
+}
+
+func handler(apiOp *types.APIRequest) error {
+	c, err := upgrader.Upgrade(apiOp.Response, apiOp.Request, nil)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	watches := NewWatchSession(apiOp)
+	defer watches.Close()
+
+	events := watches.Watch(c)
+	t := time.NewTicker(30 * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := writeData(apiOp, c, event); err != nil {
+				return err
+			}
+		case <-t.C:
+			if err := writeData(apiOp, c, types.APIEvent{Name: "ping"}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeData(apiOp *types.APIRequest, c *websocket.Conn, event types.APIEvent) error {
+	// events of a subscription are already marshalled with its own request
+	if event.Data == nil {
+		event = subscribe.MarshallObject(apiOp, event)
+	}
+	if event.Error != nil {
+		event.Name = "resource.error"
+		event.Data = map[string]interface{}{
+			"error": event.Error.Error(),
+		}
+	}
+
+	messageWriter, err := c.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	defer messageWriter.Close()
+
+	return json.NewEncoder(messageWriter).Encode(event)
+}
@@ -0,0 +1,19 @@
+package subscribe
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// Register adds the subscribe schema, it replaces the apiserver one so each subscription
+// can pick its own fields. The package follows github.com/rancher/apiserver/pkg/subscribe,
+// fixes made there need to be made here too.
+func Register(schemas *types.APISchemas) {
+	schemas.MustImportAndCustomize(Subscribe{}, func(schema *types.APISchema) {
+		schema.CollectionMethods = []string{http.MethodGet}
+		schema.ResourceMethods = []string{}
+		schema.ListHandler = Handler
+		schema.PluralName = "subscribe"
+	})
+}
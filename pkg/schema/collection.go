@@ -10,7 +10,9 @@ import (
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/metrics"
 	"github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/summary"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/cache"
@@ -52,6 +54,9 @@ type Template struct {
 	Store        types.Store
 	Start        func(ctx context.Context) error
 	StoreFactory func(types.Store) types.Store
+	// Summarizer computes metadata.state for objects of the schema, it runs after the
+	// wrangler summarizers and can override their result
+	Summarizer summary.Summarizer
 }
 
 func WrapServer(factory Factory, server *server.Server) http.Handler {
@@ -132,6 +137,8 @@ func (c *Collection) Reset(schemas map[string]*types.APISchema) {
 		c.cache.Remove(k)
 	}
 	c.lock.Unlock()
+	metrics.SetSchemas(len(schemas))
+	metrics.SetSchemaCacheEntries(0)
 	c.lock.RLock()
 	for _, f := range c.notifiers {
 		f()
@@ -223,6 +230,28 @@ func (c *Collection) ByGVK(gvk schema.GroupVersionKind) string {
 	return c.byGVK[gvk]
 }
 
+// Summarizer returns the summarizer of the first template of the GVK that has one
+func (c *Collection) Summarizer(gvk schema.GroupVersionKind) summary.Summarizer {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	templates := [][]*Template{
+		c.templates[gvk.Group+"/"+gvk.Kind],
+	}
+	if id, ok := c.byGVK[gvk]; ok {
+		templates = append([][]*Template{c.templates[id]}, templates...)
+	}
+
+	for _, templates := range templates {
+		for _, t := range templates {
+			if t.Summarizer != nil {
+				return t.Summarizer
+			}
+		}
+	}
+	return nil
+}
+
 func (c *Collection) AddTemplate(templates ...Template) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -5,6 +5,7 @@ import (
 
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/kv"
 	"github.com/rancher/wrangler/pkg/merr"
 	"github.com/rancher/wrangler/pkg/schemas"
 	"github.com/sirupsen/logrus"
@@ -20,6 +21,14 @@ var (
 	preferredVersionOverride = map[string]string{
 		"autoscaling/v1": "v2beta2",
 	}
+	// subresources that are not objects and need a dedicated handler
+	streamingSubresources = map[string]bool{
+		"attach":      true,
+		"exec":        true,
+		"log":         true,
+		"portforward": true,
+		"proxy":       true,
+	}
 )
 
 func AddDiscovery(client discovery.DiscoveryInterface, schemasMap map[string]*types.APISchema) error {
@@ -64,6 +73,7 @@ func indexVersions(groups []*metav1.APIGroup) map[string]string {
 }
 
 func refresh(gv schema.GroupVersion, groupToPreferredVersion map[string]string, resources *metav1.APIResourceList, schemasMap map[string]*types.APISchema) error {
+	byResource := map[string]*types.APISchema{}
 	for _, resource := range resources.APIResources {
 		if strings.Contains(resource.Name, "/") {
 			continue
@@ -96,6 +106,17 @@ func refresh(gv schema.GroupVersion, groupToPreferredVersion map[string]string,
 		}
 
 		schemasMap[schema.ID] = schema
+		byResource[resource.Name] = schema
+	}
+
+	for _, resource := range resources.APIResources {
+		parent, subresource := kv.Split(resource.Name, "/")
+		if subresource == "" || streamingSubresources[subresource] {
+			continue
+		}
+		if schema := byResource[parent]; schema != nil {
+			attributes.AddSubresource(schema, subresource, resource.Verbs)
+		}
 	}
 
 	return nil
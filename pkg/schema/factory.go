@@ -9,6 +9,7 @@ import (
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/metrics"
 	"k8s.io/apiserver/pkg/authentication/user"
 )
 
@@ -35,6 +36,9 @@ func (c *Collection) Schemas(user user.Info) (*types.APISchemas, error) {
 	}
 
 	c.cache.Add(access.ID, schemas, 24*time.Hour)
+	if metrics.Enabled() {
+		metrics.SetSchemaCacheEntries(len(c.cache.Keys()))
+	}
 	return schemas, nil
 }
 
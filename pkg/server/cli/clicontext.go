@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"time"
 
 	steveauth "github.com/rancher/steve/pkg/auth"
 	authcli "github.com/rancher/steve/pkg/auth/cli"
@@ -18,6 +19,22 @@ type Config struct {
 	HTTPSListenPort int
 	HTTPListenPort  int
 	UIPath          string
+	// ContinueTokenKey signs list continue tokens
+	ContinueTokenKey      string
+	ContinueTokenBindUser bool
+	HealthRollup          bool
+	// CountHistoryResolution and CountHistoryRetention configure the counthistory schema
+	CountHistoryResolution time.Duration
+	CountHistoryRetention  time.Duration
+	// ClusterCacheInclude and ClusterCacheExclude filter the types the cluster cache watches
+	ClusterCacheInclude     cli.StringSlice
+	ClusterCacheExclude     cli.StringSlice
+	ClusterCacheLazy        bool
+	ClusterCacheIdleTimeout time.Duration
+	Metrics                 bool
+	// ListCacheTypes are the schema IDs whose lists are served from an informer cache
+	ListCacheTypes       cli.StringSlice
+	ListCacheIdleTimeout time.Duration
 
 	WebhookConfig authcli.WebhookConfig
 }
@@ -49,8 +66,20 @@ func (c *Config) ToServer(ctx context.Context) (*server.Server, error) {
 	}
 
 	return server.New(ctx, restConfig, &server.Options{
-		AuthMiddleware: auth,
-		Next:           ui.New(c.UIPath),
+		AuthMiddleware:          auth,
+		Next:                    ui.New(c.UIPath),
+		ContinueTokenKey:        c.ContinueTokenKey,
+		ContinueTokenBindUser:   c.ContinueTokenBindUser,
+		HealthRollup:            c.HealthRollup,
+		CountHistoryResolution:  c.CountHistoryResolution,
+		CountHistoryRetention:   c.CountHistoryRetention,
+		ClusterCacheInclude:     c.ClusterCacheInclude,
+		ClusterCacheExclude:     c.ClusterCacheExclude,
+		ClusterCacheLazy:        c.ClusterCacheLazy,
+		ClusterCacheIdleTimeout: c.ClusterCacheIdleTimeout,
+		Metrics:                 c.Metrics,
+		ListCacheTypes:          c.ListCacheTypes,
+		ListCacheIdleTimeout:    c.ListCacheIdleTimeout,
 	})
 }
 
@@ -80,6 +109,80 @@ func Flags(config *Config) []cli.Flag {
 			Value:       9080,
 			Destination: &config.HTTPListenPort,
 		},
+		cli.StringFlag{
+			Name:        "continue-token-key",
+			EnvVar:      "CONTINUE_TOKEN_KEY",
+			Usage:       "Key to sign list continue tokens with, defaults to a random key",
+			Destination: &config.ContinueTokenKey,
+		},
+		cli.BoolTFlag{
+			Name:        "continue-token-bind-user",
+			EnvVar:      "CONTINUE_TOKEN_BIND_USER",
+			Usage:       "Only accept a list continue token from the user that listed",
+			Destination: &config.ContinueTokenBindUser,
+		},
+		cli.BoolFlag{
+			Name:        "health-rollup",
+			EnvVar:      "HEALTH_ROLLUP",
+			Usage:       "Add the health of their pods to the state of workloads, services and namespaces",
+			Destination: &config.HealthRollup,
+		},
+		cli.DurationFlag{
+			Name:        "count-history-resolution",
+			EnvVar:      "COUNT_HISTORY_RESOLUTION",
+			Usage:       "How often counts are sampled for the counthistory schema, 0 disables sampling",
+			Destination: &config.CountHistoryResolution,
+		},
+		cli.DurationFlag{
+			Name:        "count-history-retention",
+			EnvVar:      "COUNT_HISTORY_RETENTION",
+			Usage:       "How long count samples are kept",
+			Value:       24 * time.Hour,
+			Destination: &config.CountHistoryRetention,
+		},
+		cli.StringSliceFlag{
+			Name:   "cluster-cache-include",
+			EnvVar: "CLUSTER_CACHE_INCLUDE",
+			Usage:  "Only watch the types matching these kind.group patterns in the cluster cache, such as *.apps or secret",
+			Value:  &config.ClusterCacheInclude,
+		},
+		cli.StringSliceFlag{
+			Name:   "cluster-cache-exclude",
+			EnvVar: "CLUSTER_CACHE_EXCLUDE",
+			Usage:  "Never watch the types matching these kind.group patterns in the cluster cache",
+			Value:  &config.ClusterCacheExclude,
+		},
+		cli.BoolFlag{
+			Name:        "cluster-cache-lazy",
+			EnvVar:      "CLUSTER_CACHE_LAZY",
+			Usage:       "Only watch a type in the cluster cache once it is first needed",
+			Destination: &config.ClusterCacheLazy,
+		},
+		cli.DurationFlag{
+			Name:        "cluster-cache-idle-timeout",
+			EnvVar:      "CLUSTER_CACHE_IDLE_TIMEOUT",
+			Usage:       "Stop watching types in the cluster cache that were not needed for this long, 0 never stops them",
+			Destination: &config.ClusterCacheIdleTimeout,
+		},
+		cli.BoolFlag{
+			Name:        "metrics",
+			EnvVar:      "METRICS",
+			Usage:       "Serve prometheus metrics on /metrics",
+			Destination: &config.Metrics,
+		},
+		cli.StringSliceFlag{
+			Name:   "list-cache-types",
+			EnvVar: "LIST_CACHE_TYPES",
+			Usage:  "Schema IDs, such as pod, whose lists are served from an informer cache with sort, filter and pagination",
+			Value:  &config.ListCacheTypes,
+		},
+		cli.DurationFlag{
+			Name:        "list-cache-idle-timeout",
+			EnvVar:      "LIST_CACHE_IDLE_TIMEOUT",
+			Usage:       "Stop caching a type that was not listed for this long, 0 never stops",
+			Value:       30 * time.Minute,
+			Destination: &config.ListCacheIdleTimeout,
+		},
 	}
 
 	return append(flags, authcli.Flags(&config.WebhookConfig)...)
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"bufio"
+	"fmt"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/rancher/apiserver/pkg/server"
 	apiserver "github.com/rancher/apiserver/pkg/server"
@@ -9,6 +13,8 @@ import (
 	"github.com/rancher/apiserver/pkg/urlbuilder"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/auth"
+	"github.com/rancher/steve/pkg/health"
+	"github.com/rancher/steve/pkg/metrics"
 	k8sproxy "github.com/rancher/steve/pkg/proxy"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/server/router"
@@ -17,12 +23,30 @@ import (
 	"k8s.io/client-go/rest"
 )
 
+type Option func(*options)
+
+type options struct {
+	checks *health.Health
+}
+
+// WithHealth serves /healthz and /readyz, and the status page to authenticated users, from
+// the checks
+func WithHealth(checks *health.Health) Option {
+	return func(o *options) {
+		o.checks = checks
+	}
+}
+
 func New(cfg *rest.Config, sf schema.Factory, authMiddleware auth.Middleware, next http.Handler,
-	routerFunc router.RouterFunc) (*apiserver.Server, http.Handler, error) {
+	routerFunc router.RouterFunc, opts ...Option) (*apiserver.Server, http.Handler, error) {
 	var (
 		proxy http.Handler
 		err   error
+		o     options
 	)
+	for _, opt := range opts {
+		opt(&o)
+	}
 
 	a := &apiServer{
 		sf:     sf,
@@ -47,6 +71,14 @@ func New(cfg *rest.Config, sf schema.Factory, authMiddleware auth.Middleware, ne
 		K8sProxy:    w(proxy),
 		APIRoot:     w(a.apiHandler(apiRoot)),
 	}
+	if metrics.Enabled() {
+		handlers.Metrics = w(metrics.Handler())
+	}
+	if o.checks != nil {
+		handlers.Healthz = o.checks.Healthz()
+		handlers.Readyz = o.checks.Readyz()
+		handlers.Status = w(o.checks.StatusPage())
+	}
 	if routerFunc == nil {
 		return a.server, router.Routes(handlers), nil
 	}
@@ -86,16 +118,93 @@ func (a *apiServer) common(rw http.ResponseWriter, req *http.Request) (*types.AP
 	}, true
 }
 
+// unknownSchema is the schema label of requests whose type did not match a schema
+const unknownSchema = "unknown"
+
 type APIFunc func(schema.Factory, *types.APIRequest)
 
 func (a *apiServer) apiHandler(apiFunc APIFunc) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if metrics.Enabled() {
+			start := time.Now()
+			srw := &statusResponseWriter{ResponseWriter: rw, status: http.StatusOK, schema: unknownSchema}
+			rw = srw
+			defer func() {
+				metrics.ObserveAPIRequest(srw.schema, srw.verb, srw.status, start)
+			}()
+		}
+
 		apiOp, ok := a.common(rw, req)
 		if ok {
 			if apiFunc != nil {
 				apiFunc(a.sf, apiOp)
 			}
 			a.server.Handle(apiOp)
+			if srw, ok := rw.(*statusResponseWriter); ok {
+				srw.verb = toVerb(apiOp)
+				// the type comes from the URL, only known schemas are used as a label so
+				// clients can not create series
+				if apiOp.Schema != nil {
+					srw.schema = apiOp.Schema.ID
+				}
+			}
 		}
 	})
 }
+
+// toVerb returns the verb of a handled request for metrics
+func toVerb(apiOp *types.APIRequest) string {
+	switch {
+	case apiOp.Action != "":
+		return "action"
+	case apiOp.Link != "":
+		return "link"
+	}
+
+	switch apiOp.Method {
+	case http.MethodGet:
+		if apiOp.Name == "" {
+			return "list"
+		}
+		return "get"
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	}
+	// the method comes from the client, it is not used as a label either
+	return "other"
+}
+
+// statusResponseWriter records the status of a response, it passes hijacking through for
+// websockets
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+	schema string
+	verb   string
+}
+
+func (s *statusResponseWriter) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusResponseWriter) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *statusResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	s.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/aggregation"
+	"github.com/rancher/steve/pkg/clustercache"
+	schemacontroller "github.com/rancher/steve/pkg/controllers/schema"
+	"github.com/rancher/steve/pkg/health"
+)
+
+func schemaCheck(status *schemacontroller.Status) health.Checker {
+	return func() health.Check {
+		check := health.Check{
+			Name:  "schemas",
+			Ready: status.Refreshed(),
+		}
+		if err := status.LastError(); err != nil {
+			check.Message = err.Error()
+		} else if !check.Ready {
+			check.Message = "schemas have not been refreshed yet"
+		}
+		return check
+	}
+}
+
+func clusterCacheCheck(ccache clustercache.ClusterCache) health.Checker {
+	return func() health.Check {
+		check := health.Check{
+			Name:  "clusterCache",
+			Ready: true,
+		}
+		extended, ok := ccache.(clustercache.Extended)
+		if !ok {
+			return check
+		}
+		for _, gvk := range extended.Unsynced() {
+			check.Unsynced = append(check.Unsynced, gvk.String())
+		}
+		if len(check.Unsynced) > 0 {
+			sort.Strings(check.Unsynced)
+			check.Ready = false
+			check.Message = fmt.Sprintf("%d informers have not synced", len(check.Unsynced))
+		}
+		return check
+	}
+}
+
+func rbacCheck(store *accesscontrol.AccessStore) health.Checker {
+	return func() health.Check {
+		check := health.Check{
+			Name:  "rbac",
+			Ready: store.HasSynced(),
+		}
+		if !check.Ready {
+			check.Message = "roles and bindings have not synced"
+		}
+		return check
+	}
+}
+
+func aggregationCheck(status *aggregation.Status) health.Checker {
+	return func() health.Check {
+		check := health.Check{
+			Name:  "aggregation",
+			Ready: status.Connected(),
+		}
+		if !check.Ready {
+			check.Message = "not connected to the aggregation server"
+			if err := status.LastError(); err != nil {
+				check.Message += ": " + err.Error()
+			}
+		}
+		return check
+	}
+}
@@ -14,6 +14,12 @@ type Handlers struct {
 	APIRoot     http.Handler
 	K8sProxy    http.Handler
 	Next        http.Handler
+	// Metrics serves /metrics if set
+	Metrics http.Handler
+	// Healthz, Readyz and Status serve /healthz, /readyz and /statusz if set
+	Healthz http.Handler
+	Readyz  http.Handler
+	Status  http.Handler
 }
 
 func Routes(h Handlers) http.Handler {
@@ -38,6 +44,18 @@ func Routes(h Handlers) http.Handler {
 	m.PathPrefix("/apis").Handler(h.K8sProxy)
 	m.PathPrefix("/openapi").Handler(h.K8sProxy)
 	m.PathPrefix("/version").Handler(h.K8sProxy)
+	if h.Metrics != nil {
+		m.Path("/metrics").Handler(h.Metrics)
+	}
+	if h.Healthz != nil {
+		m.Path("/healthz").Handler(h.Healthz)
+	}
+	if h.Readyz != nil {
+		m.Path("/readyz").Handler(h.Readyz)
+	}
+	if h.Status != nil {
+		m.Path("/statusz").Handler(h.Status)
+	}
 	m.NotFoundHandler = h.Next
 
 	return m
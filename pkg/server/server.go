@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	apiserver "github.com/rancher/apiserver/pkg/server"
 	"github.com/rancher/apiserver/pkg/types"
@@ -14,13 +15,21 @@ import (
 	"github.com/rancher/steve/pkg/client"
 	"github.com/rancher/steve/pkg/clustercache"
 	schemacontroller "github.com/rancher/steve/pkg/controllers/schema"
+	"github.com/rancher/steve/pkg/health"
+	"github.com/rancher/steve/pkg/metrics"
 	"github.com/rancher/steve/pkg/resources"
+	"github.com/rancher/steve/pkg/resources/accessreview"
 	"github.com/rancher/steve/pkg/resources/common"
+	"github.com/rancher/steve/pkg/resources/counts"
+	"github.com/rancher/steve/pkg/resources/pod"
 	"github.com/rancher/steve/pkg/resources/schemas"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/server/handler"
 	"github.com/rancher/steve/pkg/server/router"
+	"github.com/rancher/steve/pkg/stores/partition"
+	"github.com/rancher/steve/pkg/stores/proxy"
 	"github.com/rancher/steve/pkg/summarycache"
+	"github.com/sirupsen/logrus"
 	"k8s.io/client-go/rest"
 )
 
@@ -29,8 +38,11 @@ var ErrConfigRequired = errors.New("rest config is required")
 type Server struct {
 	http.Handler
 
-	ClientFactory   *client.Factory
-	ClusterCache    clustercache.ClusterCache
+	ClientFactory *client.Factory
+	ClusterCache  clustercache.ClusterCache
+	SummaryCache  *summarycache.SummaryCache
+	// Health holds the readiness checks, embedders can add their own
+	Health          *health.Health
 	SchemaFactory   schema.Factory
 	RESTConfig      *rest.Config
 	BaseSchemas     *types.APISchemas
@@ -46,12 +58,25 @@ type Server struct {
 
 	aggregationSecretNamespace string
 	aggregationSecretName      string
+	continueTokenKey           string
+	continueTokenBindUser      bool
+	healthRollup               bool
+	countHistoryResolution     time.Duration
+	countHistoryRetention      time.Duration
+	clusterCacheOptions        clustercache.Options
+	listCacheTypes             []string
+	terminalAuditor            pod.Auditor
+	listCacheIdleTimeout       time.Duration
 }
 
 type Options struct {
 	// Controllers If the controllers are passed in the caller must also start the controllers
-	Controllers                *Controllers
-	ClientFactory              *client.Factory
+	Controllers *Controllers
+	// ClientFactory defaults to one built from the rest config, the requests of a factory from
+	// client.NewFactory are recorded in the upstream metrics whenever it was built
+	ClientFactory *client.Factory
+	// AccessSetLookup defaults to an access store on the RBAC controllers, the accessreview and
+	// whocan schemas are only added if it implements accesscontrol.Explainer
 	AccessSetLookup            accesscontrol.AccessSetLookup
 	AuthMiddleware             auth.Middleware
 	Next                       http.Handler
@@ -59,12 +84,51 @@ type Options struct {
 	AggregationSecretNamespace string
 	AggregationSecretName      string
 	ClusterRegistry            string
+	// ContinueTokenKey signs the continue tokens of lists, it must be the same on every replica
+	// behind a load balancer. A random key is used if empty.
+	ContinueTokenKey string
+	// ContinueTokenBindUser only accepts a continue token from the user that listed
+	ContinueTokenBindUser bool
+	// HealthRollup adds the health of the pods of workloads, services and namespaces to their
+	// metadata.state
+	HealthRollup bool
+	// CountHistoryResolution is how often counts are sampled into the counthistory schema,
+	// sampling is disabled if zero
+	CountHistoryResolution time.Duration
+	// CountHistoryRetention is how long count samples are kept
+	CountHistoryRetention time.Duration
+	// ClusterCacheInclude and ClusterCacheExclude are kind.group glob patterns, such as
+	// *.fleet.cattle.io, of the types the cluster cache watches. All types are watched if
+	// ClusterCacheInclude is empty.
+	ClusterCacheInclude []string
+	ClusterCacheExclude []string
+	// ClusterCacheLazy only watches a type once it is first read from the cluster cache, or
+	// once it is needed for relationships, counts or count history
+	ClusterCacheLazy bool
+	// ClusterCacheIdleTimeout stops watching types that were not read for that long, zero
+	// never stops them. Types needed for relationships, watched counts or count history are not
+	// stopped, enabling count history keeps every type watched.
+	ClusterCacheIdleTimeout time.Duration
+	// Metrics serves prometheus metrics on /metrics
+	Metrics bool
+	// ListCacheTypes are the schema IDs whose lists are answered from an informer cache
+	// instead of the kube-apiserver
+	ListCacheTypes []string
+	// ListCacheIdleTimeout stops the informer of a cached type that was not listed for that
+	// long, zero never stops them
+	ListCacheIdleTimeout time.Duration
+	// TerminalAuditor receives a record of every pod exec and attach session, they are
+	// logged as JSON if nil
+	TerminalAuditor pod.Auditor
 }
 
 func New(ctx context.Context, restConfig *rest.Config, opts *Options) (*Server, error) {
 	if opts == nil {
 		opts = &Options{}
 	}
+	if opts.Metrics {
+		metrics.Register()
+	}
 
 	server := &Server{
 		RESTConfig:                 restConfig,
@@ -77,6 +141,20 @@ func New(ctx context.Context, restConfig *rest.Config, opts *Options) (*Server,
 		aggregationSecretNamespace: opts.AggregationSecretNamespace,
 		aggregationSecretName:      opts.AggregationSecretName,
 		ClusterRegistry:            opts.ClusterRegistry,
+		continueTokenKey:           opts.ContinueTokenKey,
+		continueTokenBindUser:      opts.ContinueTokenBindUser,
+		healthRollup:               opts.HealthRollup,
+		countHistoryResolution:     opts.CountHistoryResolution,
+		countHistoryRetention:      opts.CountHistoryRetention,
+		clusterCacheOptions: clustercache.Options{
+			Include:     opts.ClusterCacheInclude,
+			Exclude:     opts.ClusterCacheExclude,
+			Lazy:        opts.ClusterCacheLazy,
+			IdleTimeout: opts.ClusterCacheIdleTimeout,
+		},
+		listCacheTypes:       opts.ListCacheTypes,
+		listCacheIdleTimeout: opts.ListCacheIdleTimeout,
+		terminalAuditor:      opts.TerminalAuditor,
 	}
 
 	if err := setup(ctx, server); err != nil {
@@ -126,25 +204,68 @@ func setup(ctx context.Context, server *Server) error {
 		server.ClientFactory = cf
 	}
 
+	server.Health = health.New()
+
 	asl := server.AccessSetLookup
 	if asl == nil {
-		asl = accesscontrol.NewAccessStore(ctx, true, server.controllers.RBAC)
+		accessStore := accesscontrol.NewAccessStore(ctx, true, server.controllers.RBAC)
+		server.Health.Add(rbacCheck(accessStore))
+		asl = accessStore
+	}
+	// the accessreview and whocan schemas need a lookup that can explain the access it computes
+	if explainer, ok := asl.(accesscontrol.Explainer); ok {
+		accessreview.Register(server.BaseSchemas, explainer)
 	}
 
 	ccache := clustercache.NewClusterCache(ctx, cf.AdminDynamicClient())
+	if extended, ok := ccache.(clustercache.Extended); ok {
+		extended.SetOptions(server.clusterCacheOptions)
+	}
 	server.ClusterCache = ccache
 	sf := schema.NewCollection(ctx, server.BaseSchemas, asl)
 
 	if err = resources.DefaultSchemas(ctx, server.BaseSchemas, ccache, server.ClientFactory, sf); err != nil {
 		return err
 	}
+	if server.countHistoryResolution > 0 {
+		retention := server.countHistoryRetention
+		if retention <= 0 {
+			retention = 24 * time.Hour
+		}
+		counts.RegisterHistory(ctx, server.BaseSchemas, ccache, sf, server.countHistoryResolution, retention)
+	}
 
 	summaryCache := summarycache.New(sf, ccache)
+	if server.healthRollup {
+		summaryCache.EnableHealthRollup()
+	}
 	summaryCache.Start(ctx)
+	server.SummaryCache = summaryCache
+
+	if server.continueTokenKey == "" {
+		logrus.Info("No continue token key is set, list continue tokens are only valid on this replica")
+	}
+	tokens, err := partition.NewTokens([]byte(server.continueTokenKey), 0, server.continueTokenBindUser)
+	if err != nil {
+		return err
+	}
 
-	for _, template := range resources.DefaultSchemaTemplates(cf, server.BaseSchemas, summaryCache, asl, server.controllers.K8s.Discovery()) {
+	for _, template := range resources.DefaultSchemaTemplates(cf, server.BaseSchemas, summaryCache, asl, server.controllers.K8s.Discovery(),
+		common.WithContinueTokens(tokens), common.WithClusterCache(ccache)) {
 		sf.AddTemplate(template)
 	}
+	if server.terminalAuditor != nil {
+		sf.AddTemplate(pod.AuditTemplate(server.terminalAuditor))
+	}
+	if len(server.listCacheTypes) > 0 {
+		cacheStoreFactory := proxy.NewCacheStoreFactory(ctx, cf, summaryCache, server.listCacheIdleTimeout)
+		for _, id := range server.listCacheTypes {
+			sf.AddTemplate(schema.Template{
+				ID:           id,
+				StoreFactory: cacheStoreFactory,
+			})
+		}
+	}
 
 	cols, err := common.NewDynamicColumns(server.RESTConfig)
 	if err != nil {
@@ -153,7 +274,7 @@ func setup(ctx context.Context, server *Server) error {
 
 	schemas.SetupWatcher(ctx, server.BaseSchemas, asl, sf)
 
-	schemacontroller.Register(ctx,
+	schemaStatus := schemacontroller.Register(ctx,
 		cols,
 		server.controllers.K8s.Discovery(),
 		server.controllers.CRD.CustomResourceDefinition(),
@@ -161,8 +282,10 @@ func setup(ctx context.Context, server *Server) error {
 		server.controllers.K8s.AuthorizationV1().SelfSubjectAccessReviews(),
 		ccache,
 		sf)
+	server.Health.Add(schemaCheck(schemaStatus), clusterCacheCheck(ccache))
 
-	apiServer, handler, err := handler.New(server.RESTConfig, sf, server.authMiddleware, server.next, server.router)
+	apiServer, handler, err := handler.New(server.RESTConfig, sf, server.authMiddleware, server.next, server.router,
+		handler.WithHealth(server.Health))
 	if err != nil {
 		return err
 	}
@@ -183,8 +306,11 @@ func (c *Server) start(ctx context.Context) error {
 }
 
 func (c *Server) StartAggregation(ctx context.Context) {
-	aggregation.Watch(ctx, c.controllers.Core.Secret(), c.aggregationSecretNamespace,
+	status := aggregation.Watch(ctx, c.controllers.Core.Secret(), c.aggregationSecretNamespace,
 		c.aggregationSecretName, c)
+	if status != nil {
+		c.Health.Add(aggregationCheck(status))
+	}
 }
 
 func (c *Server) ListenAndServe(ctx context.Context, httpsPort, httpPort int, opts *server.ListenOpts) error {
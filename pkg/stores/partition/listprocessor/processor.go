@@ -0,0 +1,362 @@
+package listprocessor
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/data/convert"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+)
+
+const (
+	filterParam   = "filter"
+	sortParam     = "sort"
+	pageSizeParam = "pagesize"
+	pageParam     = "page"
+)
+
+type ListOptions struct {
+	Filters    []Filter
+	Sort       Sort
+	Pagination Pagination
+}
+
+type FilterOp string
+
+const (
+	Eq       FilterOp = "="
+	NotEq    FilterOp = "!="
+	Contains FilterOp = "~"
+	In       FilterOp = "=in="
+	NotIn    FilterOp = "=notin="
+)
+
+// Filter matches a field against a value, the supported forms are
+// field=value, field!=value, field~value, field=in=(a,b) and field=notin=(a,b)
+type Filter struct {
+	field   []string
+	op      FilterOp
+	matches []string
+}
+
+func (f Filter) Field() []string {
+	return f.field
+}
+
+func (f Filter) String() string {
+	field := strings.Join(f.field, ".")
+	switch f.op {
+	case In, NotIn:
+		return field + string(f.op) + "(" + strings.Join(f.matches, ",") + ")"
+	default:
+		return field + string(f.op) + strings.Join(f.matches, ",")
+	}
+}
+
+type SortOrder int
+
+const (
+	ASC SortOrder = iota
+	DESC
+)
+
+type Sort struct {
+	fields []sortField
+}
+
+type sortField struct {
+	field []string
+	order SortOrder
+}
+
+type Pagination struct {
+	pageSize int
+	page     int
+}
+
+func (p Pagination) PageSize() int {
+	return p.pageSize
+}
+
+// ParseQuery parses the filter, sort and pagination query parameters, a filter that can not
+// be parsed is an InvalidOption error rather than being ignored
+func ParseQuery(apiOp *types.APIRequest) (*ListOptions, error) {
+	q := apiOp.Request.URL.Query()
+
+	var filterOpts []Filter
+	for _, filter := range q[filterParam] {
+		f, ok := parseFilter(filter)
+		if !ok {
+			return nil, apierror.NewAPIError(validation.InvalidOption, "invalid filter: "+filter)
+		}
+		filterOpts = append(filterOpts, f)
+	}
+
+	var sortOpts Sort
+	for _, s := range strings.Split(q.Get(sortParam), ",") {
+		if s == "" {
+			continue
+		}
+		order := ASC
+		if strings.HasPrefix(s, "-") {
+			order = DESC
+			s = s[1:]
+		}
+		sortOpts.fields = append(sortOpts.fields, sortField{field: ParseField(s), order: order})
+	}
+
+	pagination := Pagination{}
+	pagination.pageSize, _ = strconv.Atoi(q.Get(pageSizeParam))
+	pagination.page, _ = strconv.Atoi(q.Get(pageParam))
+
+	return &ListOptions{
+		Filters:    filterOpts,
+		Sort:       sortOpts,
+		Pagination: pagination,
+	}, nil
+}
+
+func parseFilter(filter string) (Filter, bool) {
+	i := operatorIndex(filter)
+	if i <= 0 {
+		return Filter{}, false
+	}
+
+	result := Filter{
+		field: ParseField(filter[:i]),
+	}
+	rest := filter[i:]
+
+	for _, op := range []FilterOp{In, NotIn} {
+		if !strings.HasPrefix(rest, string(op)) {
+			continue
+		}
+		// a set operator without a closed list is an error, not an equality match on "in=(a"
+		if !strings.HasPrefix(rest, string(op)+"(") || !strings.HasSuffix(rest, ")") {
+			return Filter{}, false
+		}
+		result.op = op
+		result.matches = strings.Split(rest[len(op)+1:len(rest)-1], ",")
+		return result, true
+	}
+
+	for _, op := range []FilterOp{NotEq, Contains, Eq} {
+		if strings.HasPrefix(rest, string(op)) {
+			result.op = op
+			result.matches = []string{rest[len(op):]}
+			return result, true
+		}
+	}
+
+	return Filter{}, false
+}
+
+// operatorIndex returns the start of the filter operator, skipping over bracketed keys
+func operatorIndex(filter string) int {
+	depth := 0
+	for i, c := range filter {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '!', '~', '=':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// ParseField splits a dotted path into keys, keys in brackets may contain dots
+// such as metadata.labels[app.kubernetes.io/name]
+func ParseField(field string) (result []string) {
+	for field != "" {
+		switch {
+		case field[0] == '.':
+			field = field[1:]
+		case field[0] == '[':
+			end := strings.Index(field, "]")
+			if end < 0 {
+				return append(result, field[1:])
+			}
+			result = append(result, field[1:end])
+			field = field[end+1:]
+		default:
+			end := strings.IndexAny(field, ".[")
+			if end < 0 {
+				return append(result, field)
+			}
+			result = append(result, field[:end])
+			field = field[end:]
+		}
+	}
+	return result
+}
+
+func FilterList(list []types.APIObject, filters []Filter) []types.APIObject {
+	if len(filters) == 0 {
+		return list
+	}
+	result := make([]types.APIObject, 0, len(list))
+	for _, obj := range list {
+		if matchesAll(obj.Data(), filters) {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+func matchesAll(obj map[string]interface{}, filters []Filter) bool {
+	for _, f := range filters {
+		if !matchesOne(obj, f) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesOne(obj map[string]interface{}, filter Filter) bool {
+	var values []string
+	switch typed := getValue(obj, filter.field...).(type) {
+	case nil:
+	case []interface{}:
+		for _, v := range typed {
+			values = append(values, convert.ToString(v))
+		}
+	default:
+		values = append(values, convert.ToString(typed))
+	}
+
+	switch filter.op {
+	case NotEq, NotIn:
+		return !anyMatch(values, filter.matches, equals)
+	case Contains:
+		return anyMatch(values, filter.matches, strings.Contains)
+	default:
+		return anyMatch(values, filter.matches, equals)
+	}
+}
+
+func equals(left, right string) bool {
+	return left == right
+}
+
+func anyMatch(values, matches []string, f func(string, string) bool) bool {
+	for _, value := range values {
+		for _, match := range matches {
+			if f(value, match) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func SortList(list []types.APIObject, s Sort) []types.APIObject {
+	if len(s.fields) == 0 {
+		return list
+	}
+	datas := make([]data.Object, len(list))
+	for i := range list {
+		datas[i] = list[i].Data()
+	}
+	sort.Stable(&sorter{list: list, datas: datas, fields: s.fields})
+	return list
+}
+
+type sorter struct {
+	list   []types.APIObject
+	datas  []data.Object
+	fields []sortField
+}
+
+func (s *sorter) Len() int {
+	return len(s.list)
+}
+
+func (s *sorter) Swap(i, j int) {
+	s.list[i], s.list[j] = s.list[j], s.list[i]
+	s.datas[i], s.datas[j] = s.datas[j], s.datas[i]
+}
+
+func (s *sorter) Less(i, j int) bool {
+	for _, f := range s.fields {
+		c := compare(getValue(s.datas[i], f.field...), getValue(s.datas[j], f.field...))
+		if c == 0 {
+			continue
+		}
+		if f.order == DESC {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+// getValue is like data.GetValueN but numeric keys will also index into slices
+// such as metadata.fields[2]
+func getValue(obj map[string]interface{}, keys ...string) interface{} {
+	var val interface{} = obj
+	for _, key := range keys {
+		switch typed := val.(type) {
+		case map[string]interface{}:
+			val = typed[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(typed) {
+				return nil
+			}
+			val = typed[i]
+		default:
+			return nil
+		}
+	}
+	return val
+}
+
+func compare(left, right interface{}) int {
+	leftStr, rightStr := convert.ToString(left), convert.ToString(right)
+	leftNum, leftErr := strconv.ParseFloat(leftStr, 64)
+	rightNum, rightErr := strconv.ParseFloat(rightStr, 64)
+	if leftErr == nil && rightErr == nil {
+		switch {
+		case leftNum < rightNum:
+			return -1
+		case leftNum > rightNum:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(leftStr, rightStr)
+}
+
+// PaginateList returns the requested page and the total number of pages
+func PaginateList(list []types.APIObject, p Pagination) ([]types.APIObject, int) {
+	if p.pageSize <= 0 {
+		return list, 1
+	}
+	page := p.page - 1
+	if p.page < 1 {
+		page = 0
+	}
+	pages := len(list) / p.pageSize
+	if len(list)%p.pageSize != 0 {
+		pages++
+	}
+	offset := p.pageSize * page
+	if offset > len(list) {
+		return []types.APIObject{}, pages
+	}
+	end := offset + p.pageSize
+	if end > len(list) {
+		end = len(list)
+	}
+	return list[offset:end], pages
+}
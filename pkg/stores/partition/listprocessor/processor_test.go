@@ -0,0 +1,219 @@
+package listprocessor
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func request(query string) *types.APIRequest {
+	req, _ := http.NewRequest(http.MethodGet, "/v1/pods?"+query, nil)
+	return &types.APIRequest{Request: req}
+}
+
+func objects(names ...string) []types.APIObject {
+	var result []types.APIObject
+	for _, name := range names {
+		result = append(result, types.APIObject{
+			ID: name,
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name": name,
+				},
+			},
+		})
+	}
+	return result
+}
+
+func ids(objs []types.APIObject) []string {
+	result := []string{}
+	for _, obj := range objs {
+		result = append(result, obj.ID)
+	}
+	return result
+}
+
+func TestSortList(t *testing.T) {
+	list := func() []types.APIObject {
+		return []types.APIObject{
+			{ID: "a", Object: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(10), "zone": "b"}}},
+			{ID: "b", Object: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2), "zone": "a"}}},
+			{ID: "c", Object: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2), "zone": "b"}}},
+			{ID: "d", Object: map[string]interface{}{"spec": map[string]interface{}{"zone": "a"}}},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "no sort keeps order", query: "", want: []string{"a", "b", "c", "d"}},
+		{name: "numbers sort numerically", query: "sort=spec.replicas", want: []string{"d", "b", "c", "a"}},
+		{name: "descending", query: "sort=-spec.replicas", want: []string{"a", "b", "c", "d"}},
+		{name: "multiple fields", query: "sort=spec.zone,-spec.replicas", want: []string{"b", "d", "a", "c"}},
+		{name: "missing field sorts first", query: "sort=spec.missing", want: []string{"a", "b", "c", "d"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := ParseQuery(request(test.query))
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, ids(SortList(list(), opts.Sort)))
+		})
+	}
+}
+
+func TestPaginateList(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		want      []string
+		wantPages int
+	}{
+		{name: "no page size", query: "", want: []string{"a", "b", "c", "d", "e"}, wantPages: 1},
+		{name: "first page", query: "pagesize=2", want: []string{"a", "b"}, wantPages: 3},
+		{name: "second page", query: "pagesize=2&page=2", want: []string{"c", "d"}, wantPages: 3},
+		{name: "short last page", query: "pagesize=2&page=3", want: []string{"e"}, wantPages: 3},
+		{name: "past the end", query: "pagesize=2&page=4", want: []string{}, wantPages: 3},
+		{name: "invalid page is the first", query: "pagesize=2&page=0", want: []string{"a", "b"}, wantPages: 3},
+		{name: "exact pages", query: "pagesize=5", want: []string{"a", "b", "c", "d", "e"}, wantPages: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := ParseQuery(request(test.query))
+			assert.NoError(t, err)
+			page, pages := PaginateList(objects("a", "b", "c", "d", "e"), opts.Pagination)
+			assert.Equal(t, test.want, ids(page))
+			assert.Equal(t, test.wantPages, pages)
+		})
+	}
+}
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		field string
+		want  []string
+	}{
+		{field: "metadata.name", want: []string{"metadata", "name"}},
+		{field: "metadata.labels[app.kubernetes.io/name]", want: []string{"metadata", "labels", "app.kubernetes.io/name"}},
+		{field: "metadata.fields[2]", want: []string{"metadata", "fields", "2"}},
+		{field: "[a.b].c", want: []string{"a.b", "c"}},
+		{field: "a[unterminated", want: []string{"a", "unterminated"}},
+		{field: "", want: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.field, func(t *testing.T) {
+			assert.Equal(t, test.want, ParseField(test.field))
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+		ok     bool
+	}{
+		{filter: "metadata.name=foo", want: "metadata.name=foo", ok: true},
+		{filter: "metadata.name!=foo", want: "metadata.name!=foo", ok: true},
+		{filter: "metadata.name~fo", want: "metadata.name~fo", ok: true},
+		{filter: "metadata.namespace=in=(a,b)", want: "metadata.namespace=in=(a,b)", ok: true},
+		{filter: "metadata.namespace=notin=(a)", want: "metadata.namespace=notin=(a)", ok: true},
+		{filter: "metadata.labels[app.kubernetes.io/name]=web", want: "metadata.labels.app.kubernetes.io/name=web", ok: true},
+		{filter: "metadata.name=", want: "metadata.name=", ok: true},
+		{filter: "=foo", ok: false},
+		{filter: "metadata.name", ok: false},
+		{filter: "metadata.name=in=(a", ok: false},
+		{filter: "metadata.name=notin=a,b", ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			f, ok := parseFilter(test.filter)
+			assert.Equal(t, test.ok, ok)
+			if ok {
+				assert.Equal(t, test.want, f.String())
+			}
+		})
+	}
+}
+
+func TestParseQueryFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "no filters"},
+		{name: "filters", query: "filter=metadata.name=a&filter=metadata.namespace!=b", want: []string{"metadata.name=a", "metadata.namespace!=b"}},
+		{name: "unclosed list", query: "filter=metadata.name=in=(a", wantErr: true},
+		{name: "missing operator", query: "filter=metadata.name&filter=metadata.namespace=a", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := ParseQuery(request(test.query))
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var filters []string
+			for _, f := range opts.Filters {
+				filters = append(filters, f.String())
+			}
+			assert.Equal(t, test.want, filters)
+		})
+	}
+}
+
+func TestFilterList(t *testing.T) {
+	list := func() []types.APIObject {
+		return []types.APIObject{
+			{ID: "a", Object: map[string]interface{}{"metadata": map[string]interface{}{
+				"namespace": "default",
+				"labels":    map[string]interface{}{"app.kubernetes.io/name": "web"},
+				"fields":    []interface{}{"a", "Running"},
+			}}},
+			{ID: "b", Object: map[string]interface{}{"metadata": map[string]interface{}{
+				"namespace": "kube-system",
+				"labels":    map[string]interface{}{"app.kubernetes.io/name": "dns"},
+				"fields":    []interface{}{"b", "Pending"},
+			}}},
+			{ID: "c", Object: map[string]interface{}{"metadata": map[string]interface{}{
+				"namespace":  "default",
+				"finalizers": []interface{}{"x", "y"},
+			}}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filters []string
+		want    []string
+	}{
+		{name: "no filters", want: []string{"a", "b", "c"}},
+		{name: "equals", filters: []string{"metadata.namespace=default"}, want: []string{"a", "c"}},
+		{name: "not equals", filters: []string{"metadata.namespace!=default"}, want: []string{"b"}},
+		{name: "contains", filters: []string{"metadata.namespace~system"}, want: []string{"b"}},
+		{name: "in", filters: []string{"metadata.labels[app.kubernetes.io/name]=in=(web,dns)"}, want: []string{"a", "b"}},
+		{name: "not in matches missing", filters: []string{"metadata.labels[app.kubernetes.io/name]=notin=(web)"}, want: []string{"b", "c"}},
+		{name: "slice index", filters: []string{"metadata.fields[1]=Running"}, want: []string{"a"}},
+		{name: "any slice value", filters: []string{"metadata.finalizers=y"}, want: []string{"c"}},
+		{name: "all filters must match", filters: []string{"metadata.namespace=default", "metadata.fields[1]=Running"}, want: []string{"a"}},
+		{name: "missing field", filters: []string{"spec.missing=x"}, want: []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var filters []Filter
+			for _, filter := range test.filters {
+				f, ok := parseFilter(filter)
+				assert.True(t, ok)
+				filters = append(filters, f)
+			}
+			assert.Equal(t, test.want, ids(FilterList(list(), filters)))
+		})
+	}
+}
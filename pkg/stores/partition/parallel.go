@@ -2,12 +2,12 @@ package partition
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
+	"sync/atomic"
 
 	"github.com/rancher/apiserver/pkg/types"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/semaphore"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 type Partition interface {
@@ -18,9 +18,14 @@ type ParallelPartitionLister struct {
 	Lister      PartitionLister
 	Concurrency int64
 	Partitions  []Partition
-	state       *listState
-	revision    string
-	err         error
+	Tokens      *Tokens
+	// Binding is signed into continue tokens, a token is only accepted with the same binding
+	Binding  string
+	state    *listState
+	revision string
+	err      error
+	// inconsistent is set when a resumed partition had to be relisted at the latest revision
+	inconsistent int32
 }
 
 type PartitionLister func(ctx context.Context, partition Partition, cont string, revision string, limit int) (types.APIObjectList, error)
@@ -33,15 +38,21 @@ func (p *ParallelPartitionLister) Revision() string {
 	return p.revision
 }
 
+// Inconsistent is true if the revision of a resumed list was compacted, the partitions that
+// were relisted at the latest revision may repeat or miss objects
+func (p *ParallelPartitionLister) Inconsistent() bool {
+	return atomic.LoadInt32(&p.inconsistent) == 1
+}
+
 func (p *ParallelPartitionLister) Continue() string {
 	if p.state == nil {
 		return ""
 	}
-	bytes, err := json.Marshal(p.state)
+	token, err := p.Tokens.encode(p.state, p.Binding)
 	if err != nil {
 		return ""
 	}
-	return base64.StdEncoding.EncodeToString(bytes)
+	return token
 }
 
 func indexOrZero(partitions []Partition, name string) int {
@@ -59,13 +70,11 @@ func indexOrZero(partitions []Partition, name string) int {
 func (p *ParallelPartitionLister) List(ctx context.Context, limit int, resume string) (<-chan []types.APIObject, error) {
 	var state listState
 	if resume != "" {
-		bytes, err := base64.StdEncoding.DecodeString(resume)
+		var err error
+		state, err = p.Tokens.decode(resume, p.Binding)
 		if err != nil {
 			return nil, err
 		}
-		if err := json.Unmarshal(bytes, &state); err != nil {
-			return nil, err
-		}
 
 		if state.Limit > 0 {
 			limit = state.Limit
@@ -88,7 +97,7 @@ type listState struct {
 func (p *ParallelPartitionLister) feeder(ctx context.Context, state listState, limit int, result chan []types.APIObject) {
 	var (
 		sem      = semaphore.NewWeighted(p.Concurrency)
-		capacity = limit
+		capacity = int64(limit)
 		last     chan struct{}
 	)
 
@@ -102,7 +111,7 @@ func (p *ParallelPartitionLister) feeder(ctx context.Context, state listState, l
 	}()
 
 	for i := indexOrZero(p.Partitions, state.PartitionName); i < len(p.Partitions); i++ {
-		if capacity <= 0 || isDone(ctx) {
+		if atomic.LoadInt64(&capacity) <= 0 || isDone(ctx) {
 			break
 		}
 
@@ -118,16 +127,22 @@ func (p *ParallelPartitionLister) feeder(ctx context.Context, state listState, l
 
 		if state.Revision == "" {
 			// don't have a revision yet so grab all tickets to set a revision
-			tickets = 3
+			tickets = p.Concurrency
 		}
 		if err := sem.Acquire(ctx, tickets); err != nil {
 			p.err = err
 			break
 		}
 
-		// make state local
-		state := state
+		nextPartition := ""
+		if i+1 < len(p.Partitions) {
+			nextPartition = p.Partitions[i+1].Name()
+		}
+
+		// make state local, the copy must not shadow state for the rest of the loop
+		local := state
 		eg.Go(func() error {
+			state := local
 			defer sem.Release(tickets)
 			defer close(next)
 
@@ -137,6 +152,13 @@ func (p *ParallelPartitionLister) feeder(ctx context.Context, state listState, l
 					cont = state.Continue
 				}
 				list, err := p.Lister(ctx, partition, cont, state.Revision, limit)
+				if err != nil && state.Revision != "" && isExpired(err) {
+					// the revision or upstream token of the resumed list was compacted, so
+					// this partition is listed again from the start at the latest revision
+					atomic.StoreInt32(&p.inconsistent, 1)
+					cont, state.Revision, state.Continue, state.Offset = "", "", "", 0
+					list, err = p.Lister(ctx, partition, "", "", limit)
+				}
 				if err != nil {
 					return err
 				}
@@ -158,22 +180,32 @@ func (p *ParallelPartitionLister) feeder(ctx context.Context, state listState, l
 					list.Objects = list.Objects[state.Offset:]
 				}
 
-				if len(list.Objects) > capacity {
-					result <- list.Objects[:capacity]
+				// partitions take turns so only the loop above reads capacity concurrently
+				room := int(atomic.LoadInt64(&capacity))
+				if len(list.Objects) > room {
+					result <- list.Objects[:room]
 					// save state to redo this list at this offset
 					p.state = &listState{
-						Revision:      list.Revision,
+						Revision:      state.Revision,
 						PartitionName: partition.Name(),
 						Continue:      cont,
-						Offset:        capacity,
+						Offset:        room,
 						Limit:         limit,
 					}
-					capacity = 0
+					atomic.StoreInt64(&capacity, 0)
 					return nil
 				} else {
 					result <- list.Objects
-					capacity -= len(list.Objects)
+					atomic.AddInt64(&capacity, -int64(len(list.Objects)))
 					if list.Continue == "" {
+						if len(list.Objects) == room && nextPartition != "" {
+							// the page ended with this partition, resume at the next one
+							p.state = &listState{
+								Revision:      state.Revision,
+								PartitionName: nextPartition,
+								Limit:         limit,
+							}
+						}
 						return nil
 					}
 					// loop again and get more data
@@ -183,11 +215,22 @@ func (p *ParallelPartitionLister) feeder(ctx context.Context, state listState, l
 				}
 			}
 		})
+
+		if state.Revision == "" {
+			// every later partition is listed at the revision of the first one so the
+			// combined list is a single snapshot
+			waitForTurn(ctx, next)
+			state.Revision = p.revision
+		}
 	}
 
 	p.err = eg.Wait()
 }
 
+func isExpired(err error) bool {
+	return apierrors.IsResourceExpired(err) || apierrors.IsGone(err)
+}
+
 func waitForTurn(ctx context.Context, turn chan struct{}) {
 	if turn == nil {
 		return
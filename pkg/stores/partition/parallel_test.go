@@ -0,0 +1,209 @@
+package partition
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+type testPartition string
+
+func (t testPartition) Name() string {
+	return string(t)
+}
+
+// testLister pages through the objects of each partition, the upstream continue token is
+// the offset and revisions in expired fail like a compacted revision
+type testLister struct {
+	sync.Mutex
+	objects   map[string][]string
+	latest    string
+	expired   map[string]bool
+	revisions []string
+}
+
+func (l *testLister) list(_ context.Context, partition Partition, cont string, revision string, limit int) (types.APIObjectList, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.revisions = append(l.revisions, revision)
+	if l.expired[revision] {
+		return types.APIObjectList{}, apierrors.NewResourceExpired("too old")
+	}
+	if revision == "" {
+		revision = l.latest
+	}
+
+	objs := l.objects[partition.Name()]
+	start, _ := strconv.Atoi(cont)
+	end := len(objs)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+
+	result := types.APIObjectList{Revision: revision}
+	for _, name := range objs[start:end] {
+		result.Objects = append(result.Objects, types.APIObject{ID: name})
+	}
+	if end < len(objs) {
+		result.Continue = strconv.Itoa(end)
+	}
+	return result, nil
+}
+
+func listPage(t *testing.T, tokens *Tokens, l *testLister, limit int, resume string) ([]string, *ParallelPartitionLister, error) {
+	lister := &ParallelPartitionLister{
+		Lister:      l.list,
+		Concurrency: 3,
+		Partitions:  []Partition{testPartition("a"), testPartition("b"), testPartition("c")},
+		Tokens:      tokens,
+		Binding:     "pod",
+	}
+	c, err := lister.List(context.Background(), limit, resume)
+	if err != nil {
+		return nil, lister, err
+	}
+	result := []string{}
+	for objs := range c {
+		for _, obj := range objs {
+			result = append(result, obj.ID)
+		}
+	}
+	assert.NoError(t, lister.Err())
+	return result, lister, nil
+}
+
+func TestParallelPartitionListerPages(t *testing.T) {
+	objects := map[string][]string{
+		"a": {"a1", "a2", "a3", "a4"},
+		"b": {"b1", "b2"},
+		"c": {"c1", "c2", "c3"},
+	}
+	all := []string{"a1", "a2", "a3", "a4", "b1", "b2", "c1", "c2", "c3"}
+
+	tests := []struct {
+		name      string
+		limit     int
+		unsigned  bool
+		wantPages int
+	}{
+		{name: "everything in one page", limit: 100, wantPages: 1},
+		{name: "page per object", limit: 1, wantPages: 9},
+		{name: "pages across partitions", limit: 3, wantPages: 3},
+		{name: "pages split partitions", limit: 4, wantPages: 3},
+		{name: "exact fit", limit: 9, wantPages: 1},
+		{name: "unsigned tokens", limit: 2, unsigned: true, wantPages: 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := NewTokens(nil, 0, false)
+			assert.NoError(t, err)
+			if test.unsigned {
+				tokens = nil
+			}
+			l := &testLister{objects: objects, latest: "5"}
+
+			var (
+				got    []string
+				pages  int
+				resume string
+			)
+			for {
+				page, lister, err := listPage(t, tokens, l, test.limit, resume)
+				assert.NoError(t, err)
+				assert.LessOrEqual(t, len(page), test.limit)
+				assert.Equal(t, "5", lister.Revision())
+				assert.False(t, lister.Inconsistent())
+				got = append(got, page...)
+				pages++
+
+				resume = lister.Continue()
+				if resume == "" || pages > len(all) {
+					break
+				}
+			}
+
+			assert.Equal(t, all, got)
+			assert.Equal(t, test.wantPages, pages)
+			// only the first list picks the revision, every later one is pinned to it
+			for _, revision := range l.revisions[1:] {
+				assert.Equal(t, "5", revision)
+			}
+		})
+	}
+}
+
+func TestParallelPartitionListerResume(t *testing.T) {
+	tests := []struct {
+		name             string
+		expire           bool
+		tamper           bool
+		binding          string
+		want             []string
+		wantInconsistent bool
+		wantErr          bool
+	}{
+		{
+			name: "resume at the pinned revision",
+			want: []string{"a3", "a4", "b1"},
+		},
+		{
+			name:             "compacted revision relists at the latest",
+			expire:           true,
+			want:             []string{"a1", "a2", "a3"},
+			wantInconsistent: true,
+		},
+		{
+			name:    "tampered token",
+			tamper:  true,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := NewTokens(nil, 0, false)
+			assert.NoError(t, err)
+			l := &testLister{
+				objects: map[string][]string{
+					"a": {"a1", "a2", "a3", "a4"},
+					"b": {"b1", "b2"},
+				},
+				latest:  "5",
+				expired: map[string]bool{},
+			}
+
+			_, first, err := listPage(t, tokens, l, 2, "")
+			assert.NoError(t, err)
+			resume := first.Continue()
+			assert.NotEmpty(t, resume)
+
+			if test.expire {
+				l.expired["5"] = true
+				l.latest = "6"
+			}
+			if test.tamper {
+				resume = "x" + resume
+			}
+
+			page, lister, err := listPage(t, tokens, l, 3, resume)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			// the limit of the first page is kept in the token
+			assert.Equal(t, test.want[:2], page[:2])
+			assert.Equal(t, test.wantInconsistent, lister.Inconsistent())
+			if test.expire {
+				assert.Equal(t, "6", lister.Revision())
+			} else {
+				assert.Equal(t, "5", lister.Revision())
+			}
+		})
+	}
+}
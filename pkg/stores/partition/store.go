@@ -6,9 +6,14 @@ import (
 	"strconv"
 
 	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/metrics"
 	"golang.org/x/sync/errgroup"
 )
 
+// InconsistentHeader is set on a list response resumed from a compacted revision, it may
+// repeat or miss objects and clients that need a consistent list should list again
+const InconsistentHeader = "X-Api-Inconsistent-List"
+
 type Partitioner interface {
 	Lookup(apiOp *types.APIRequest, schema *types.APISchema, verb, id string) (Partition, error)
 	All(apiOp *types.APIRequest, schema *types.APISchema, verb, id string) ([]Partition, error)
@@ -17,6 +22,7 @@ type Partitioner interface {
 
 type Store struct {
 	Partitioner Partitioner
+	Tokens      *Tokens
 }
 
 func (s *Store) getStore(apiOp *types.APIRequest, schema *types.APISchema, verb, id string) (types.Store, error) {
@@ -85,6 +91,8 @@ func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.AP
 		},
 		Concurrency: 3,
 		Partitions:  paritions,
+		Tokens:      s.Tokens,
+		Binding:     s.Tokens.binding(apiOp, schema),
 	}
 
 	resume := apiOp.Request.URL.Query().Get("continue")
@@ -101,6 +109,9 @@ func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.AP
 
 	result.Revision = lister.Revision()
 	result.Continue = lister.Continue()
+	if lister.Inconsistent() && apiOp.Response != nil {
+		apiOp.Response.Header().Set(InconsistentHeader, "true")
+	}
 	return result, nil
 }
 
@@ -154,8 +165,10 @@ func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, wr types
 		})
 	}
 
+	metrics.IncWatches(schema.ID)
 	go func() {
 		defer close(response)
+		defer metrics.DecWatches(schema.ID)
 		<-ctx.Done()
 		eg.Wait()
 		cancel()
@@ -0,0 +1,113 @@
+package partition
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+func TestTokens(t *testing.T) {
+	state := listState{
+		Revision:      "5",
+		PartitionName: "default",
+		Continue:      "abc",
+		Offset:        2,
+		Limit:         10,
+	}
+	signed, err := NewTokens([]byte("key"), 0, false)
+	assert.NoError(t, err)
+	otherKey, err := NewTokens([]byte("other"), 0, false)
+	assert.NoError(t, err)
+	// without a key every process signs with its own random one
+	random, otherRandom := mustTokens(t, nil, 0), mustTokens(t, nil, 0)
+
+	tests := []struct {
+		name       string
+		encode     *Tokens
+		decode     *Tokens
+		binding    string
+		tamper     func(string) string
+		wantErr    string
+		wantLegacy bool
+	}{
+		{name: "round trip", encode: signed, decode: signed, binding: "pod"},
+		{name: "random key", encode: random, decode: random, binding: "pod"},
+		{name: "other random key", encode: random, decode: otherRandom, binding: "pod", wantErr: "signature does not match"},
+		{name: "other key", encode: signed, decode: otherKey, binding: "pod", wantErr: "signature does not match"},
+		{name: "other binding", encode: signed, decode: signed, binding: "secret", wantErr: "signature does not match"},
+		{name: "expired", encode: mustTokens(t, []byte("key"), -time.Minute), decode: signed, binding: "pod", wantErr: "expired"},
+		{
+			name: "changed payload", encode: signed, decode: signed, binding: "pod",
+			tamper: func(token string) string {
+				parts := strings.Split(token, ".")
+				return parts[0] + "x." + parts[1]
+			},
+			wantErr: "signature does not match",
+		},
+		{name: "not a token", encode: signed, decode: signed, binding: "pod", tamper: func(string) string { return "abc" }, wantErr: "malformed"},
+		{name: "legacy round trip", binding: "pod", wantLegacy: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := test.encode.encode(&state, "pod")
+			assert.NoError(t, err)
+			if test.tamper != nil {
+				token = test.tamper(token)
+			}
+			if test.wantLegacy {
+				assert.NotContains(t, token, ".")
+			}
+
+			got, err := test.decode.decode(token, test.binding)
+			if test.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), test.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, state, got)
+		})
+	}
+}
+
+func mustTokens(t *testing.T, key []byte, ttl time.Duration) *Tokens {
+	tokens, err := NewTokens(key, ttl, false)
+	assert.NoError(t, err)
+	if ttl < 0 {
+		tokens.ttl = ttl
+	}
+	return tokens
+}
+
+func TestTokensBinding(t *testing.T) {
+	schema := &types.APISchema{Schema: &schemas.Schema{ID: "pod"}}
+	tests := []struct {
+		name   string
+		tokens *Tokens
+		user   string
+		want   string
+	}{
+		{name: "nil tokens", tokens: nil, user: "alice", want: ""},
+		{name: "schema only", tokens: &Tokens{}, user: "alice", want: "pod"},
+		{name: "bound to the user", tokens: &Tokens{bindUser: true}, user: "alice", want: "pod\x00alice"},
+		{name: "bound without a user", tokens: &Tokens{bindUser: true}, want: "pod"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.user != "" {
+				ctx = request.WithUser(ctx, &user.DefaultInfo{Name: test.user})
+			}
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/v1/pods", nil)
+			assert.Equal(t, test.want, test.tokens.binding(&types.APIRequest{Request: req}, schema))
+		})
+	}
+}
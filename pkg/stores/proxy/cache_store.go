@@ -0,0 +1,231 @@
+package proxy
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/stores/partition"
+	"github.com/rancher/steve/pkg/stores/partition/listprocessor"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	TotalCountHeader = "X-Api-Total-Count"
+	PageCountHeader  = "X-Api-Page-Count"
+)
+
+type informerCache struct {
+	sync.Mutex
+
+	ctx          context.Context
+	clientGetter ClientGetter
+	computed     ComputedFields
+	idleTimeout  time.Duration
+	informers    map[schema.GroupVersionResource]*cachedInformer
+}
+
+type cachedInformer struct {
+	informer cache.SharedIndexInformer
+	cancel   func()
+	lastUsed time.Time
+}
+
+// NewCacheStoreFactory returns a StoreFactory for schema.Template that answers List
+// from a shared per type informer cache instead of the kube-apiserver. Informers that
+// were not listed from for idleTimeout are stopped, zero never stops them. computed may
+// be nil, filters on computed fields then only match what the objects hold.
+func NewCacheStoreFactory(ctx context.Context, clientGetter ClientGetter, computed ComputedFields, idleTimeout time.Duration) func(types.Store) types.Store {
+	informers := &informerCache{
+		ctx:          ctx,
+		clientGetter: clientGetter,
+		computed:     computed,
+		idleTimeout:  idleTimeout,
+		informers:    map[schema.GroupVersionResource]*cachedInformer{},
+	}
+	if idleTimeout > 0 {
+		go informers.stopIdle()
+	}
+	return func(next types.Store) types.Store {
+		return &cacheStore{
+			Store:     next,
+			informers: informers,
+		}
+	}
+}
+
+type cacheStore struct {
+	types.Store
+	informers *informerCache
+}
+
+func (c *informerCache) get(apiOp *types.APIRequest, schema *types.APISchema) (cache.SharedIndexInformer, error) {
+	gvr := attributes.GVR(schema)
+
+	c.Lock()
+	cached, ok := c.informers[gvr]
+	if !ok {
+		// the informer is shared by every user so it is built with the admin client and
+		// nothing of the request that starts it
+		client, err := c.clientGetter.TableAdminClientForWatch(&types.APIRequest{}, schema, "")
+		if err != nil {
+			c.Unlock()
+			return nil, err
+		}
+
+		ctx, cancel := context.WithCancel(c.ctx)
+		informer := cache.NewSharedIndexInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				list, err := client.List(ctx, options)
+				if err != nil {
+					return nil, err
+				}
+				tableToList(list)
+				return list, nil
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				w, err := client.Watch(ctx, options)
+				if err != nil {
+					return nil, err
+				}
+				return watch.Filter(w, func(in watch.Event) (watch.Event, bool) {
+					if unstr, ok := in.Object.(*unstructured.Unstructured); ok {
+						rowToObject(unstr)
+					}
+					return in, true
+				}), nil
+			},
+		}, &unstructured.Unstructured{}, 2*time.Hour, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+
+		logrus.Infof("Caching objects for %s", gvr)
+		cached = &cachedInformer{
+			informer: informer,
+			cancel:   cancel,
+		}
+		c.informers[gvr] = cached
+		go informer.Run(ctx.Done())
+	}
+	cached.lastUsed = time.Now()
+	c.Unlock()
+
+	if !cache.WaitForCacheSync(apiOp.Context().Done(), cached.informer.HasSynced) {
+		return nil, apiOp.Context().Err()
+	}
+
+	return cached.informer, nil
+}
+
+func (c *informerCache) stopIdle() {
+	t := time.NewTicker(c.idleTimeout / 2)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case now := <-t.C:
+			c.removeIdle(now)
+		}
+	}
+}
+
+func (c *informerCache) removeIdle(now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	for gvr, cached := range c.informers {
+		if now.Sub(cached.lastUsed) > c.idleTimeout {
+			logrus.Infof("Stopping idle object cache for %s", gvr)
+			cached.cancel()
+			delete(c.informers, gvr)
+		}
+	}
+}
+
+func (s *cacheStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	informer, err := s.informers.get(apiOp, schema)
+	if err != nil {
+		return types.APIObjectList{}, translateError(err)
+	}
+
+	partitions, passthrough := isPassthrough(apiOp, schema, "list")
+	if passthrough {
+		partitions = []partition.Partition{
+			Partition{
+				Namespace: apiOp.Namespace,
+				All:       true,
+			},
+		}
+	}
+
+	selector, err := labels.Parse(apiOp.Request.URL.Query().Get("labelSelector"))
+	if err != nil {
+		return types.APIObjectList{}, apierror.NewAPIError(validation.InvalidOption, err.Error())
+	}
+	opts, err := listprocessor.ParseQuery(apiOp)
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+
+	var objs []types.APIObject
+	for _, p := range partitions {
+		p := p.(Partition)
+		items, err := cachedItems(informer, p.Namespace, (passthrough && p.Namespace == "") || p.Namespace == accesscontrol.All)
+		if err != nil {
+			return types.APIObjectList{}, err
+		}
+		for _, item := range items {
+			unstr, ok := item.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			if !selector.Matches(labels.Set(unstr.GetLabels())) {
+				continue
+			}
+			if !p.All && !p.Names.Has(unstr.GetName()) {
+				continue
+			}
+			objs = append(objs, toAPI(schema, unstr.DeepCopy()))
+		}
+	}
+
+	objs = filterList(s.informers.computed, objs, opts.Filters)
+	objs = listprocessor.SortList(objs, opts.Sort)
+	total := len(objs)
+	objs, pages := listprocessor.PaginateList(objs, opts.Pagination)
+
+	setCountHeaders(apiOp, total, pages)
+	return types.APIObjectList{
+		Revision: informer.LastSyncResourceVersion(),
+		Objects:  objs,
+	}, nil
+}
+
+func cachedItems(informer cache.SharedIndexInformer, namespace string, all bool) ([]interface{}, error) {
+	if all {
+		return informer.GetStore().List(), nil
+	}
+	return informer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+}
+
+func setCountHeaders(apiOp *types.APIRequest, total, pages int) {
+	if apiOp.Response == nil {
+		return
+	}
+	header := apiOp.Response.Header()
+	header.Set(TotalCountHeader, strconv.Itoa(total))
+	header.Set(PageCountHeader, strconv.Itoa(pages))
+}
@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestInformerCacheRemoveIdle(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		lastUsed time.Duration
+		want     bool
+	}{
+		{name: "recently used", lastUsed: time.Minute, want: true},
+		{name: "at the timeout", lastUsed: 10 * time.Minute, want: true},
+		{name: "idle", lastUsed: 11 * time.Minute, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			c := &informerCache{
+				idleTimeout: 10 * time.Minute,
+				informers: map[schema.GroupVersionResource]*cachedInformer{
+					gvr: {
+						cancel:   cancel,
+						lastUsed: now.Add(-test.lastUsed),
+					},
+				},
+			}
+			c.removeIdle(now)
+
+			_, kept := c.informers[gvr]
+			assert.Equal(t, test.want, kept)
+			assert.Equal(t, !test.want, ctx.Err() != nil)
+		})
+	}
+}
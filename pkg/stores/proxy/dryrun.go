@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/wrangler/pkg/data"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	// fields that always differ or are added by steve and would only add noise to a diff
+	diffIgnoredPaths = map[string]bool{
+		"/metadata/managedFields":   true,
+		"/metadata/resourceVersion": true,
+		"/metadata/fields":          true,
+	}
+)
+
+func isDryRun(dryRun []string) bool {
+	for _, v := range dryRun {
+		if v == metav1.DryRunAll {
+			return true
+		}
+	}
+	return false
+}
+
+// liveObject returns the current object so a dry run result can be compared to it, nil is
+// returned if the object does not exist. Other errors are returned as the diff would be
+// wrong without the object.
+func liveObject(ctx context.Context, client dynamic.ResourceInterface, name string, subresources ...string) (*unstructured.Unstructured, error) {
+	if name == "" {
+		return nil, nil
+	}
+	obj, err := client.Get(ctx, name, metav1.GetOptions{}, subresources...)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	rowToObject(obj)
+	return obj, nil
+}
+
+// addDiff stores the changes between the live object and the dry run result in
+// metadata.diff of the result
+func addDiff(live, result *unstructured.Unstructured) {
+	if result == nil {
+		return
+	}
+
+	liveData := map[string]interface{}{}
+	if live != nil {
+		liveData = live.Object
+	}
+
+	changes := []interface{}{}
+	diff("", liveData, result.Object, func(op, path string, oldValue, newValue interface{}) {
+		change := map[string]interface{}{
+			"op":   op,
+			"path": path,
+		}
+		// values are copied as they may be part of result which the diff is added to
+		if oldValue != nil {
+			change["old"] = runtime.DeepCopyJSONValue(oldValue)
+		}
+		if newValue != nil {
+			change["new"] = runtime.DeepCopyJSONValue(newValue)
+		}
+		changes = append(changes, change)
+	})
+
+	data.PutValue(result.Object, changes, "metadata", "diff")
+}
+
+// addRemoveDiff marks every field of obj as removed, which is the result of a dry run delete
+func addRemoveDiff(obj *unstructured.Unstructured) {
+	if obj == nil {
+		return
+	}
+	removed := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	addDiff(obj, removed)
+	data.PutValue(obj.Object, data.GetValueN(removed.Object, "metadata", "diff"), "metadata", "diff")
+}
+
+func diff(path string, left, right interface{}, emit func(op, path string, oldValue, newValue interface{})) {
+	if diffIgnoredPaths[path] || reflect.DeepEqual(left, right) {
+		return
+	}
+
+	switch {
+	case left == nil:
+		emit("add", path, nil, right)
+		return
+	case right == nil:
+		emit("remove", path, left, nil)
+		return
+	}
+
+	leftMap, leftOK := left.(map[string]interface{})
+	rightMap, rightOK := right.(map[string]interface{})
+	if leftOK && rightOK {
+		for _, k := range sortedKeys(leftMap, rightMap) {
+			diff(path+"/"+escapePointer(k), leftMap[k], rightMap[k], emit)
+		}
+		return
+	}
+
+	leftSlice, leftOK := left.([]interface{})
+	rightSlice, rightOK := right.([]interface{})
+	if leftOK && rightOK && len(leftSlice) == len(rightSlice) {
+		for i := range leftSlice {
+			diff(path+"/"+strconv.Itoa(i), leftSlice[i], rightSlice[i], emit)
+		}
+		return
+	}
+
+	emit("replace", path, left, right)
+}
+
+func sortedKeys(left, right map[string]interface{}) (result []string) {
+	seen := map[string]bool{}
+	for _, m := range []map[string]interface{}{left, right} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				result = append(result, k)
+			}
+		}
+	}
+	sort.Strings(result)
+	return
+}
+
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// getClient answers every get with obj or err
+type getClient struct {
+	dynamic.ResourceInterface
+	obj *unstructured.Unstructured
+	err error
+}
+
+func (g *getClient) Get(context.Context, string, metav1.GetOptions, ...string) (*unstructured.Unstructured, error) {
+	return g.obj, g.err
+}
+
+func TestLiveObject(t *testing.T) {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	pod.SetName("web")
+	podsGR := schema.GroupResource{Resource: "pods"}
+
+	tests := []struct {
+		name     string
+		id       string
+		client   *getClient
+		wantLive bool
+		wantErr  bool
+	}{
+		{name: "found", id: "web", client: &getClient{obj: pod}, wantLive: true},
+		{name: "no name", client: &getClient{obj: pod}},
+		{name: "not found", id: "web", client: &getClient{err: apierrors.NewNotFound(podsGR, "web")}},
+		{name: "forbidden", id: "web", client: &getClient{err: apierrors.NewForbidden(podsGR, "web", errors.New("denied"))}, wantErr: true},
+		{name: "failure", id: "web", client: &getClient{err: errors.New("connection refused")}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			live, err := liveObject(context.Background(), test.client, test.id)
+			assert.Equal(t, test.wantErr, err != nil)
+			assert.Equal(t, test.wantLive, live != nil)
+		})
+	}
+}
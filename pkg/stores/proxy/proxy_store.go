@@ -6,31 +6,58 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"reflect"
 	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
+	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/accesscontrol"
 	"github.com/rancher/steve/pkg/attributes"
 	"github.com/rancher/steve/pkg/stores/partition"
+	"github.com/rancher/steve/pkg/stores/partition/listprocessor"
 	"github.com/rancher/wrangler/pkg/data"
 	"github.com/rancher/wrangler/pkg/schemas/validation"
 	"github.com/rancher/wrangler/pkg/summary"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
+const (
+	defaultFieldManager = "steve"
+
+	// maxNameSelectors is the most names that are read with one metadata.name field selector
+	// request each, larger sets fall back to a single list filtered by name
+	maxNameSelectors = 20
+
+	// maxNameWatches is the most names that are watched with one metadata.name field selector
+	// watch each, larger sets fall back to a single watch filtered by name
+	maxNameWatches = 5
+
+	// maxFilterPages is the most upstream pages read to fill one filtered page
+	maxFilterPages = 10
+
+	// SyncAPIEvent tells watchers that events may have been missed and they should relist
+	SyncAPIEvent = "resource.sync"
+	// BookmarkAPIEvent carries the latest revision of a watch without an object
+	BookmarkAPIEvent = "resource.bookmark"
+)
+
 var (
 	lowerChars  = regexp.MustCompile("[a-z]+")
 	paramScheme = runtime.NewScheme()
@@ -63,24 +90,47 @@ type Store struct {
 	notifier     RelationshipNotifier
 }
 
-func NewProxyStore(clientGetter ClientGetter, notifier RelationshipNotifier, lookup accesscontrol.AccessSetLookup) types.Store {
+// StoreOption configures the partitioned store of NewProxyStore
+type StoreOption func(*partition.Store)
+
+// WithContinueTokens signs the continue tokens of lists with tokens
+func WithContinueTokens(tokens *partition.Tokens) StoreOption {
+	return func(s *partition.Store) {
+		s.Tokens = tokens
+	}
+}
+
+func NewProxyStore(clientGetter ClientGetter, notifier RelationshipNotifier, lookup accesscontrol.AccessSetLookup, opts ...StoreOption) types.Store {
+	partitionStore := &partition.Store{
+		Partitioner: &rbacPartitioner{
+			proxyStore: &Store{
+				clientGetter: clientGetter,
+				notifier:     notifier,
+			},
+		},
+	}
+	for _, opt := range opts {
+		opt(partitionStore)
+	}
+
 	return &errorStore{
 		Store: &WatchRefresh{
-			Store: &partition.Store{
-				Partitioner: &rbacPartitioner{
-					proxyStore: &Store{
-						clientGetter: clientGetter,
-						notifier:     notifier,
-					},
-				},
-			},
-			asl: lookup,
+			Store: partitionStore,
+			asl:   lookup,
 		},
 	}
 }
 
 func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
-	result, err := s.byID(apiOp, schema, id)
+	var subresources []string
+	if sub, ok := subresource(apiOp, schema, "get"); ok {
+		if err := checkSubresource(apiOp, schema, "get", sub, apiOp.Namespace, id); err != nil {
+			return types.APIObject{}, err
+		}
+		subresources = append(subresources, sub)
+	}
+
+	result, err := s.byID(apiOp, schema, id, subresources...)
 	return toAPI(schema, result), err
 }
 
@@ -117,7 +167,7 @@ func toAPI(schema *types.APISchema, obj runtime.Object) types.APIObject {
 	return apiObject
 }
 
-func (s *Store) byID(apiOp *types.APIRequest, schema *types.APISchema, id string) (*unstructured.Unstructured, error) {
+func (s *Store) byID(apiOp *types.APIRequest, schema *types.APISchema, id string, subresources ...string) (*unstructured.Unstructured, error) {
 	k8sClient, err := s.clientGetter.TableClient(apiOp, schema, apiOp.Namespace)
 	if err != nil {
 		return nil, err
@@ -128,7 +178,7 @@ func (s *Store) byID(apiOp *types.APIRequest, schema *types.APISchema, id string
 		return nil, err
 	}
 
-	obj, err := k8sClient.Get(apiOp.Context(), id, opts)
+	obj, err := k8sClient.Get(apiOp.Context(), id, opts, subresources...)
 	rowToObject(obj)
 	return obj, err
 }
@@ -218,6 +268,10 @@ func (s *Store) ByNames(apiOp *types.APIRequest, schema *types.APISchema, names
 		return types.APIObjectList{}, err
 	}
 
+	if names.Len() <= maxNameSelectors {
+		return s.listNames(apiOp, schema, adminClient, names)
+	}
+
 	objs, err := s.list(apiOp, schema, adminClient)
 	if err != nil {
 		return types.APIObjectList{}, err
@@ -234,6 +288,41 @@ func (s *Store) ByNames(apiOp *types.APIRequest, schema *types.APISchema, names
 	return objs, nil
 }
 
+// listNames lists each name with a metadata.name field selector so only the objects the
+// user was granted are read from the kube-apiserver.
+func (s *Store) listNames(apiOp *types.APIRequest, schema *types.APISchema, client dynamic.ResourceInterface, names sets.String) (types.APIObjectList, error) {
+	var result types.APIObjectList
+	for _, name := range names.List() {
+		req := withFieldSelector(apiOp, "metadata.name="+name)
+		// a name matches at most one object so the result is never continued
+		values := req.Request.URL.Query()
+		values.Del("continue")
+		req.Request.URL.RawQuery = values.Encode()
+
+		objs, err := s.list(req, schema, client)
+		if err != nil {
+			return types.APIObjectList{}, err
+		}
+		// lists run in order so the last revision is the newest
+		result.Revision = objs.Revision
+		result.Objects = append(result.Objects, objs.Objects...)
+	}
+	return result, nil
+}
+
+// withFieldSelector returns a copy of apiOp with selector added to the fieldSelector query parameter
+func withFieldSelector(apiOp *types.APIRequest, selector string) *types.APIRequest {
+	apiOp = apiOp.Clone()
+	apiOp.Request = apiOp.Request.Clone(apiOp.Context())
+	q := apiOp.Request.URL.Query()
+	if existing := q.Get("fieldSelector"); existing != "" {
+		selector = existing + "," + selector
+	}
+	q.Set("fieldSelector", selector)
+	apiOp.Request.URL.RawQuery = q.Encode()
+	return apiOp
+}
+
 func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
 	client, err := s.clientGetter.TableClient(apiOp, schema, apiOp.Namespace)
 	if err != nil {
@@ -248,23 +337,95 @@ func (s *Store) list(apiOp *types.APIRequest, schema *types.APISchema, client dy
 		return types.APIObjectList{}, nil
 	}
 
-	resultList, err := client.List(apiOp.Context(), opts)
+	// the partition store pins every partition to the revision of the first one, a continue
+	// token already carries its own revision
+	if rev := apiOp.Request.URL.Query().Get("revision"); rev != "" && opts.Continue == "" {
+		opts.ResourceVersion = rev
+		opts.ResourceVersionMatch = metav1.ResourceVersionMatchExact
+	}
+
+	listOpts, err := listprocessor.ParseQuery(apiOp)
 	if err != nil {
 		return types.APIObjectList{}, err
 	}
 
-	tableToList(resultList)
+	var (
+		filters = listOpts.Filters
+		limit   = opts.Limit
+		result  types.APIObjectList
+	)
+
+	// filters apply after the upstream limit, so keep reading pages until the filtered page
+	// is full. A page may still be short after maxFilterPages reads or on the last page.
+	for pages := 1; ; pages++ {
+		resultList, err := client.List(apiOp.Context(), opts)
+		if err != nil {
+			return types.APIObjectList{}, err
+		}
+
+		tableToList(resultList)
 
-	result := types.APIObjectList{
-		Revision: resultList.GetResourceVersion(),
-		Continue: resultList.GetContinue(),
+		var objs []types.APIObject
+		for i := range resultList.Items {
+			objs = append(objs, toAPI(schema, &resultList.Items[i]))
+		}
+
+		if result.Revision == "" {
+			result.Revision = resultList.GetResourceVersion()
+		}
+		result.Continue = resultList.GetContinue()
+		result.Objects = append(result.Objects, s.filterList(objs, filters)...)
+
+		if len(filters) == 0 || limit <= 0 || result.Continue == "" || pages >= maxFilterPages ||
+			int64(len(result.Objects)) >= limit {
+			return result, nil
+		}
+
+		// only ask for what is missing so the page never exceeds the limit
+		opts.Continue = result.Continue
+		opts.Limit = limit - int64(len(result.Objects))
+		opts.ResourceVersion = ""
+		opts.ResourceVersionMatch = ""
 	}
+}
+
+// ComputedFields adds the fields the formatter computes, such as metadata.state and
+// metadata.relationships, to an object so filters can match them before it is rendered
+type ComputedFields interface {
+	AddComputedFields(obj *unstructured.Unstructured)
+}
 
-	for i := range resultList.Items {
-		result.Objects = append(result.Objects, toAPI(schema, &resultList.Items[i]))
+func (s *Store) filterList(objs []types.APIObject, filters []listprocessor.Filter) []types.APIObject {
+	computed, _ := s.notifier.(ComputedFields)
+	return filterList(computed, objs, filters)
+}
+
+// filterList applies filters to objs. Filters on computed fields need computed to add those
+// fields first, without it they only match what the object itself holds.
+func filterList(computed ComputedFields, objs []types.APIObject, filters []listprocessor.Filter) []types.APIObject {
+	if len(filters) == 0 {
+		return objs
 	}
 
-	return result, nil
+	if computed != nil && filtersComputed(filters) {
+		for _, obj := range objs {
+			if unstr, ok := obj.Object.(*unstructured.Unstructured); ok {
+				computed.AddComputedFields(unstr)
+			}
+		}
+	}
+
+	return listprocessor.FilterList(objs, filters)
+}
+
+func filtersComputed(filters []listprocessor.Filter) bool {
+	for _, filter := range filters {
+		field := filter.Field()
+		if len(field) > 1 && field[0] == "metadata" && (field[1] == "state" || field[1] == "relationships") {
+			return true
+		}
+	}
+	return false
 }
 
 func returnErr(err error, c chan types.APIEvent) {
@@ -274,93 +435,275 @@ func returnErr(err error, c chan types.APIEvent) {
 	}
 }
 
-func (s *Store) listAndWatch(apiOp *types.APIRequest, k8sClient dynamic.ResourceInterface, schema *types.APISchema, w types.WatchRequest, result chan types.APIEvent) {
+func (s *Store) latestRevision(ctx context.Context, k8sClient dynamic.ResourceInterface, rev string) (string, error) {
+	// ensure the revision is valid or get the latest one
+	list, err := k8sClient.List(ctx, metav1.ListOptions{
+		Limit:           1,
+		ResourceVersion: rev,
+	})
+	if err != nil {
+		return "", err
+	}
+	if rev == "" {
+		rev = list.GetResourceVersion()
+	}
+	return rev, nil
+}
+
+// relationshipChanges sends a modified event for each object of the schema whose inbound
+// relationships change until ctx is done, only for names if it is not nil
+func (s *Store) relationshipChanges(ctx context.Context, apiOp *types.APIRequest, schema *types.APISchema, names sets.String, result chan types.APIEvent) {
+	namespace := apiOp.Namespace
+	if namespace == "" && attributes.Namespaced(schema) {
+		// an empty namespace only has the changes to cluster scoped objects
+		namespace = accesscontrol.All
+	}
+	for rel := range s.notifier.OnInboundRelationshipChange(ctx, schema, namespace) {
+		if names != nil && !names.Has(rel.Name) {
+			continue
+		}
+		relOp := apiOp
+		if rel.Namespace != apiOp.Namespace {
+			relOp = apiOp.Clone()
+			relOp.Namespace = rel.Namespace
+		}
+		obj, err := s.byID(relOp, schema, rel.Name)
+		if err == nil {
+			result <- s.toAPIEvent(apiOp, schema, watch.Modified, obj)
+		}
+	}
+}
+
+// listAndWatch watches the kube-apiserver and, if relationships is set, the inbound
+// relationship changes of the schema until the request is done or the watch fails
+func (s *Store) listAndWatch(apiOp *types.APIRequest, k8sClient dynamic.ResourceInterface, schema *types.APISchema, w types.WatchRequest, relationships bool, result chan types.APIEvent) {
 	rev := w.Revision
 	if rev == "-1" {
 		rev = ""
 	} else {
-		// ensure the revision is valid or get the latest one
-		list, err := k8sClient.List(apiOp.Context(), metav1.ListOptions{
-			Limit:           1,
-			ResourceVersion: rev,
-		})
+		var err error
+		rev, err = s.latestRevision(apiOp.Context(), k8sClient, rev)
 		if err != nil {
 			returnErr(errors.Wrapf(err, "failed to list %s", schema.ID), result)
 			return
 		}
-		if rev == "" {
-			rev = list.GetResourceVersion()
-		}
 	}
 
-	timeout := int64(60 * 30)
-	watcher, err := k8sClient.Watch(apiOp.Context(), metav1.ListOptions{
-		Watch:           true,
-		TimeoutSeconds:  &timeout,
-		ResourceVersion: rev,
-		LabelSelector:   w.Selector,
-	})
-	if err != nil {
-		returnErr(errors.Wrapf(err, "stopping watch for %s: %v", schema.ID, err), result)
-		return
-	}
-	defer watcher.Stop()
-	logrus.Debugf("opening watcher for %s", schema.ID)
-
 	eg, ctx := errgroup.WithContext(apiOp.Context())
 
-	go func() {
-		<-ctx.Done()
-		watcher.Stop()
-	}()
-
-	if s.notifier != nil {
+	if s.notifier != nil && relationships {
 		eg.Go(func() error {
-			for rel := range s.notifier.OnInboundRelationshipChange(ctx, schema, apiOp.Namespace) {
-				obj, err := s.byID(apiOp, schema, rel.Name)
-				if err == nil {
-					result <- s.toAPIEvent(apiOp, schema, watch.Modified, obj)
-				}
-			}
+			s.relationshipChanges(ctx, apiOp, schema, nil, result)
 			return fmt.Errorf("closed")
 		})
 	}
 
 	eg.Go(func() error {
-		for event := range watcher.ResultChan() {
-			if event.Type == watch.Error {
-				continue
+		for {
+			var (
+				expired bool
+				err     error
+			)
+			rev, expired, err = s.watchOnce(ctx, apiOp, k8sClient, schema, w, rev, result)
+			if isDone(ctx) {
+				return fmt.Errorf("closed")
+			}
+			// the revision we were watching from is gone, so events may have been missed. A
+			// watch from no revision that saw no event or bookmark has no revision to resume
+			// from either, watching from no revision again would add every object again.
+			// Either way the client is sent a sync at the latest revision.
+			if expired || (err == nil && rev == "") {
+				var latest string
+				latest, err = s.latestRevision(ctx, k8sClient, "")
+				if err == nil {
+					rev = latest
+					logrus.Debugf("no revision to resume the watch of %s from, sending sync at %s", schema.ID, rev)
+					result <- types.APIEvent{
+						Name:         SyncAPIEvent,
+						ResourceType: schema.ID,
+						Revision:     rev,
+					}
+					continue
+				}
+			}
+			if err != nil {
+				// only expired or closed watches are resumed, anything else such as a
+				// forbidden watch would fail again so the client is told and the watch ends
+				returnErr(errors.Wrapf(err, "failed to watch %s", schema.ID), result)
+				return err
 			}
-			result <- s.toAPIEvent(apiOp, schema, event.Type, event.Object)
+			logrus.Debugf("restarting closed watch for %s at %s", schema.ID, rev)
 		}
-		return fmt.Errorf("closed")
 	})
 
 	_ = eg.Wait()
 	return
 }
 
+// watchOnce runs a single upstream watch starting at rev and returns the last revision seen
+// so the caller can resume from it.
+func (s *Store) watchOnce(ctx context.Context, apiOp *types.APIRequest, k8sClient dynamic.ResourceInterface, schema *types.APISchema,
+	w types.WatchRequest, rev string, result chan types.APIEvent) (string, bool, error) {
+	timeout := int64(60 * 30)
+	watcher, err := k8sClient.Watch(ctx, metav1.ListOptions{
+		Watch:               true,
+		TimeoutSeconds:      &timeout,
+		ResourceVersion:     rev,
+		LabelSelector:       w.Selector,
+		FieldSelector:       apiOp.Request.URL.Query().Get("fieldSelector"),
+		AllowWatchBookmarks: true,
+	})
+	if err != nil {
+		return rev, isExpired(err), err
+	}
+	defer watcher.Stop()
+	logrus.Debugf("opening watcher for %s at %s", schema.ID, rev)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			watcher.Stop()
+		case <-done:
+		}
+	}()
+
+	for event := range watcher.ResultChan() {
+		switch event.Type {
+		case watch.Error:
+			err := apierrors.FromObject(event.Object)
+			return rev, isExpired(err), err
+		case watch.Bookmark:
+			if m, err := meta.Accessor(event.Object); err == nil {
+				rev = m.GetResourceVersion()
+			}
+			result <- types.APIEvent{
+				Name:         BookmarkAPIEvent,
+				ResourceType: schema.ID,
+				Revision:     rev,
+			}
+		default:
+			apiEvent := s.toAPIEvent(apiOp, schema, event.Type, event.Object)
+			if apiEvent.Revision != "" {
+				rev = apiEvent.Revision
+			}
+			result <- apiEvent
+		}
+	}
+
+	return rev, false, nil
+}
+
+func isExpired(err error) bool {
+	return apierrors.IsResourceExpired(err) || apierrors.IsGone(err)
+}
+
+func isDone(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
 func (s *Store) WatchNames(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest, names sets.String) (chan types.APIEvent, error) {
 	adminClient, err := s.clientGetter.TableAdminClientForWatch(apiOp, schema, apiOp.Namespace)
 	if err != nil {
 		return nil, err
 	}
-	c, err := s.watch(apiOp, schema, w, adminClient)
-	if err != nil {
-		return nil, err
+
+	// a single watch already carries relationship changes, bookmarks and syncs for every name
+	if names.Len() > maxNameWatches {
+		return filterNames(s.watch(apiOp, schema, w, adminClient), names), nil
+	}
+
+	ctx, cancel := context.WithCancel(apiOp.Context())
+	apiOp = apiOp.WithContext(ctx)
+
+	var watches []chan types.APIEvent
+	for _, name := range names.List() {
+		c := make(chan types.APIEvent)
+		watches = append(watches, c)
+		go func(req *types.APIRequest) {
+			s.listAndWatch(req, adminClient, schema, w, false, c)
+			close(c)
+		}(withFieldSelector(apiOp, "metadata.name="+name))
 	}
 
+	result := make(chan types.APIEvent)
+	merger := syncMerger{}
+	watchers := sync.WaitGroup{}
+	for _, c := range watches {
+		c := c
+		watchers.Add(1)
+		go func() {
+			defer watchers.Done()
+			for item := range c {
+				// bookmarks carry the revision of one name only so they are not sent on, a
+				// sync is sent once for every watch that expires at the same revision
+				switch {
+				case item.Name == BookmarkAPIEvent:
+				case item.Name == SyncAPIEvent:
+					if merger.first(item.Revision) {
+						result <- item
+					}
+				default:
+					result <- item
+				}
+			}
+		}()
+	}
+
+	relationships := sync.WaitGroup{}
+	if s.notifier != nil {
+		relationships.Add(1)
+		go func() {
+			defer relationships.Done()
+			s.relationshipChanges(ctx, apiOp, schema, names, result)
+		}()
+	}
+
+	go func() {
+		watchers.Wait()
+		cancel()
+		relationships.Wait()
+		close(result)
+	}()
+
+	return result, nil
+}
+
+// syncMerger remembers the revision of the last sync event sent
+type syncMerger struct {
+	sync.Mutex
+	revision string
+}
+
+func (m *syncMerger) first(revision string) bool {
+	m.Lock()
+	defer m.Unlock()
+	if m.revision == revision {
+		return false
+	}
+	m.revision = revision
+	return true
+}
+
+// filterNames drops the events of objects other than names from c
+func filterNames(c chan types.APIEvent, names sets.String) chan types.APIEvent {
 	result := make(chan types.APIEvent)
 	go func() {
 		defer close(result)
 		for item := range c {
-			if item.Error == nil && names.Has(item.Object.Name()) {
-				result <- item
+			if item.Error == nil && item.Object.Object != nil && !names.Has(item.Object.Name()) {
+				continue
 			}
+			result <- item
 		}
 	}()
-
-	return result, nil
+	return result
 }
 
 func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
@@ -368,17 +711,17 @@ func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.
 	if err != nil {
 		return nil, err
 	}
-	return s.watch(apiOp, schema, w, client)
+	return s.watch(apiOp, schema, w, client), nil
 }
 
-func (s *Store) watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest, client dynamic.ResourceInterface) (chan types.APIEvent, error) {
+func (s *Store) watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest, client dynamic.ResourceInterface) chan types.APIEvent {
 	result := make(chan types.APIEvent)
 	go func() {
-		s.listAndWatch(apiOp, client, schema, w, result)
+		s.listAndWatch(apiOp, client, schema, w, true, result)
 		logrus.Debugf("closing watcher for %s", schema.ID)
 		close(result)
 	}()
-	return result, nil
+	return result
 }
 
 func (s *Store) toAPIEvent(apiOp *types.APIRequest, schema *types.APISchema, et watch.EventType, obj runtime.Object) types.APIEvent {
@@ -444,6 +787,9 @@ func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, params
 
 	resp, err = k8sClient.Create(apiOp.Context(), &unstructured.Unstructured{Object: input}, opts)
 	rowToObject(resp)
+	if err == nil && isDryRun(opts.DryRun) {
+		addDiff(nil, resp)
+	}
 	return toAPI(schema, resp), err
 }
 
@@ -459,61 +805,153 @@ func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, params
 		return types.APIObject{}, err
 	}
 
+	var subresources []string
+	if sub, ok := subresource(apiOp, schema, subresourceVerb(apiOp.Method)); ok {
+		if ns == "" {
+			ns = apiOp.Namespace
+		}
+		if err := checkSubresource(apiOp, schema, subresourceVerb(apiOp.Method), sub, ns, id); err != nil {
+			return types.APIObject{}, err
+		}
+		subresources = append(subresources, sub)
+	}
+
 	if apiOp.Method == http.MethodPatch {
 		bytes, err := ioutil.ReadAll(io.LimitReader(apiOp.Request.Body, 2<<20))
 		if err != nil {
 			return types.APIObject{}, err
 		}
 
-		pType := apitypes.StrategicMergePatchType
-		if apiOp.Request.Header.Get("content-type") == string(apitypes.JSONPatchType) {
-			pType = apitypes.JSONPatchType
-		}
+		pType := patchType(apiOp.Request.Header.Get("content-type"))
 
 		opts := metav1.PatchOptions{}
 		if err := decodeParams(apiOp, &opts); err != nil {
 			return types.APIObject{}, err
 		}
+		if pType == apitypes.ApplyPatchType && opts.FieldManager == "" {
+			opts.FieldManager = defaultFieldManager
+		}
 
-		if pType == apitypes.StrategicMergePatchType {
-			data := map[string]interface{}{}
-			if err := json.Unmarshal(bytes, &data); err != nil {
-				return types.APIObject{}, err
-			}
-			data = moveFromUnderscore(data)
-			bytes, err = json.Marshal(data)
+		bytes, err = patchFromUnderscore(pType, bytes)
+		if err != nil {
+			return types.APIObject{}, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+		}
+
+		var live *unstructured.Unstructured
+		if isDryRun(opts.DryRun) {
+			live, err = liveObject(apiOp.Context(), k8sClient, id, subresources...)
 			if err != nil {
 				return types.APIObject{}, err
 			}
 		}
 
-		resp, err := k8sClient.Patch(apiOp.Context(), id, pType, bytes, opts)
+		resp, err := k8sClient.Patch(apiOp.Context(), id, pType, bytes, opts, subresources...)
 		if err != nil {
 			return types.APIObject{}, err
 		}
 
+		rowToObject(resp)
+		if isDryRun(opts.DryRun) {
+			addDiff(live, resp)
+		}
 		return toAPI(schema, resp), nil
 	}
 
 	resourceVersion := input.String("metadata", "resourceVersion")
-	if resourceVersion == "" {
+	if resourceVersion == "" && len(subresources) == 0 {
 		return types.APIObject{}, fmt.Errorf("metadata.resourceVersion is required for update")
 	}
+	if len(subresources) > 0 && types.Name(input) == "" {
+		input.SetNested(id, "metadata", "name")
+	}
 
 	opts := metav1.UpdateOptions{}
 	if err := decodeParams(apiOp, &opts); err != nil {
 		return types.APIObject{}, err
 	}
 
-	resp, err := k8sClient.Update(apiOp.Context(), &unstructured.Unstructured{Object: moveFromUnderscore(input)}, metav1.UpdateOptions{})
+	var live *unstructured.Unstructured
+	if isDryRun(opts.DryRun) {
+		live, err = liveObject(apiOp.Context(), k8sClient, id, subresources...)
+		if err != nil {
+			return types.APIObject{}, err
+		}
+	}
+
+	resp, err := k8sClient.Update(apiOp.Context(), &unstructured.Unstructured{Object: moveFromUnderscore(input)}, opts, subresources...)
 	if err != nil {
 		return types.APIObject{}, err
 	}
 
 	rowToObject(resp)
+	if isDryRun(opts.DryRun) {
+		addDiff(live, resp)
+	}
 	return toAPI(schema, resp), nil
 }
 
+func patchType(contentType string) apitypes.PatchType {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return apitypes.StrategicMergePatchType
+	}
+
+	switch apitypes.PatchType(mediaType) {
+	case apitypes.JSONPatchType:
+		return apitypes.JSONPatchType
+	case apitypes.MergePatchType:
+		return apitypes.MergePatchType
+	case apitypes.ApplyPatchType:
+		return apitypes.ApplyPatchType
+	default:
+		return apitypes.StrategicMergePatchType
+	}
+}
+
+// patchFromUnderscore translates the reserved fields that toAPI renamed with a leading
+// underscore back to their real names for each of the patch formats.
+func patchFromUnderscore(pType apitypes.PatchType, bytes []byte) ([]byte, error) {
+	if pType == apitypes.JSONPatchType {
+		var ops []map[string]interface{}
+		if err := json.Unmarshal(bytes, &ops); err != nil {
+			return nil, err
+		}
+		for _, op := range ops {
+			for _, key := range []string{"path", "from"} {
+				if path, ok := op[key].(string); ok {
+					op[key] = pathFromUnderscore(path)
+				}
+			}
+		}
+		return json.Marshal(ops)
+	}
+
+	if pType == apitypes.ApplyPatchType {
+		// apply patches may be YAML, which is a superset of JSON
+		var err error
+		bytes, err = yaml.ToJSON(bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, err
+	}
+	return json.Marshal(moveFromUnderscore(data))
+}
+
+func pathFromUnderscore(path string) string {
+	for k := range types.ReservedFields {
+		prefix := "/_" + k
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return "/" + k + strings.TrimPrefix(path, prefix)
+		}
+	}
+	return path
+}
+
 func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
 	opts := metav1.DeleteOptions{}
 	if err := decodeParams(apiOp, &opts); err != nil {
@@ -536,5 +974,8 @@ func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id stri
 			Status: http.StatusNoContent,
 		}
 	}
+	if isDryRun(opts.DryRun) {
+		addRemoveDiff(obj)
+	}
 	return toAPI(schema, obj), nil
 }
@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/summary"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// pagedClient lists objects in pages of opts.Limit, the continue token is the offset
+type pagedClient struct {
+	dynamic.ResourceInterface
+	objs  []unstructured.Unstructured
+	lists []metav1.ListOptions
+}
+
+func (p *pagedClient) List(_ context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	p.lists = append(p.lists, opts)
+	start, _ := strconv.Atoi(opts.Continue)
+	end := len(p.objs)
+	if opts.Limit > 0 && start+int(opts.Limit) < end {
+		end = start + int(opts.Limit)
+	}
+	result := &unstructured.UnstructuredList{
+		Object: map[string]interface{}{},
+		Items:  p.objs[start:end],
+	}
+	result.SetResourceVersion("10")
+	if end < len(p.objs) {
+		result.SetContinue(strconv.Itoa(end))
+	}
+	return result, nil
+}
+
+func pods(states ...string) (result []unstructured.Unstructured) {
+	for i, state := range states {
+		obj := unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetName("pod" + strconv.Itoa(i))
+		obj.SetNamespace("default")
+		obj.SetLabels(map[string]string{"state": state})
+		result = append(result, obj)
+	}
+	return result
+}
+
+// labelState computes metadata.state from the state label
+type labelState struct{}
+
+func (labelState) AddComputedFields(obj *unstructured.Unstructured) {
+	obj.Object["metadata"].(map[string]interface{})["state"] = map[string]interface{}{
+		"name": obj.GetLabels()["state"],
+	}
+}
+
+func listRequest(query string) *types.APIRequest {
+	req, _ := http.NewRequest(http.MethodGet, "/v1/pods?"+query, nil)
+	return &types.APIRequest{Request: req}
+}
+
+func names(objs []types.APIObject) []string {
+	result := []string{}
+	for _, obj := range objs {
+		result = append(result, obj.Object.(*unstructured.Unstructured).GetName())
+	}
+	return result
+}
+
+func TestListFilterFillsPage(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		notifier     RelationshipNotifier
+		states       []string
+		want         []string
+		wantContinue string
+		wantLists    int
+	}{
+		{
+			name:         "no filter reads one page",
+			query:        "limit=2",
+			states:       []string{"active", "error", "active"},
+			want:         []string{"pod0", "pod1"},
+			wantContinue: "2",
+			wantLists:    1,
+		},
+		{
+			name:         "filter reads until the page is full",
+			query:        "limit=2&filter=metadata.state.name=active",
+			notifier:     stateNotifier{},
+			states:       []string{"active", "error", "error", "error", "active", "active"},
+			want:         []string{"pod0", "pod4"},
+			wantContinue: "5",
+			wantLists:    4,
+		},
+		{
+			name:      "filter stops on the last page",
+			query:     "limit=5&filter=metadata.state.name=active",
+			notifier:  stateNotifier{},
+			states:    []string{"error", "active", "error", "error", "error", "error"},
+			want:      []string{"pod1"},
+			wantLists: 2,
+		},
+		{
+			name:      "computed filter without computed fields matches nothing",
+			query:     "limit=2&filter=metadata.state.name=active",
+			states:    []string{"active", "active"},
+			want:      []string{},
+			wantLists: 1,
+		},
+		{
+			name:      "without a limit everything is one page",
+			query:     "filter=metadata.state.name=error",
+			notifier:  stateNotifier{},
+			states:    []string{"active", "error", "error"},
+			want:      []string{"pod1", "pod2"},
+			wantLists: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &pagedClient{objs: pods(test.states...)}
+			s := &Store{notifier: test.notifier}
+
+			result, err := s.list(listRequest(test.query), &types.APISchema{Schema: &schemas.Schema{ID: "pod"}}, client)
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, names(result.Objects))
+			assert.Equal(t, test.wantContinue, result.Continue)
+			assert.Equal(t, "10", result.Revision)
+			assert.Len(t, client.lists, test.wantLists)
+		})
+	}
+}
+
+type stateNotifier struct {
+	labelState
+}
+
+func (stateNotifier) OnInboundRelationshipChange(context.Context, *types.APISchema, string) <-chan *summary.Relationship {
+	return nil
+}
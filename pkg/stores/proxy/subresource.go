@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/schemas/validation"
+	"github.com/rancher/wrangler/pkg/slice"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// subresource returns the subresource requested with a link, such as scale in
+// /v1/apps.deployments/ns/name/scale, if the subresource supports verb
+func subresource(apiOp *types.APIRequest, schema *types.APISchema, verb string) (string, bool) {
+	if apiOp.Link == "" {
+		return "", false
+	}
+	verbs, ok := attributes.Subresources(schema)[apiOp.Link]
+	if !ok || !slice.ContainsString(verbs, verb) {
+		return "", false
+	}
+	return apiOp.Link, true
+}
+
+func subresourceVerb(method string) string {
+	switch method {
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPost:
+		return "create"
+	default:
+		return "get"
+	}
+}
+
+// checkSubresource checks the access of the user to a subresource, access to the parent
+// resource does not imply access to its subresources
+func checkSubresource(apiOp *types.APIRequest, schema *types.APISchema, verb, subresource, namespace, name string) error {
+	gr := attributes.GR(schema)
+	gr.Resource += "/" + subresource
+
+	accessSet := accesscontrol.AccessSetFromAPIRequest(apiOp)
+	if accessSet == nil || !accessSet.Grants(verb, gr, namespace, name) {
+		return apierror.NewAPIError(validation.PermissionDenied, "can not "+verb+" "+gr.String())
+	}
+	return nil
+}
+
+// SubresourceAction serves subresources that can only be created, such as pods/eviction, as
+// actions of the parent resource
+type SubresourceAction struct {
+	clientGetter ClientGetter
+}
+
+func NewSubresourceAction(clientGetter ClientGetter) *SubresourceAction {
+	return &SubresourceAction{
+		clientGetter: clientGetter,
+	}
+}
+
+func (s *SubresourceAction) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiOp := types.GetAPIContext(req.Context())
+	if err := s.create(apiOp); err != nil {
+		apiOp.WriteError(err)
+	}
+}
+
+func (s *SubresourceAction) create(apiOp *types.APIRequest) error {
+	sub := apiOp.Action
+	if err := checkSubresource(apiOp, apiOp.Schema, "create", sub, apiOp.Namespace, apiOp.Name); err != nil {
+		return err
+	}
+
+	input := map[string]interface{}{}
+	if err := json.NewDecoder(io.LimitReader(apiOp.Request.Body, 2<<20)).Decode(&input); err != nil && err != io.EOF {
+		return apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	if data.GetValueN(input, "metadata", "name") == nil {
+		data.PutValue(input, apiOp.Name, "metadata", "name")
+	}
+	if data.GetValueN(input, "metadata", "namespace") == nil && apiOp.Namespace != "" {
+		data.PutValue(input, apiOp.Namespace, "metadata", "namespace")
+	}
+
+	k8sClient, err := s.clientGetter.Client(apiOp, apiOp.Schema, apiOp.Namespace)
+	if err != nil {
+		return err
+	}
+
+	opts := metav1.CreateOptions{}
+	if err := decodeParams(apiOp, &opts); err != nil {
+		return err
+	}
+
+	resp, err := k8sClient.Create(apiOp.Context(), &unstructured.Unstructured{Object: input}, opts, sub)
+	if err != nil {
+		return translateError(err)
+	}
+
+	apiOp.WriteResponse(http.StatusCreated, toAPI(apiOp.Schema, resp))
+	return nil
+}
+
+// AddSubresourceActions adds an action for each subresource of schema that can be created
+func AddSubresourceActions(schema *types.APISchema, clientGetter ClientGetter) {
+	for name, verbs := range attributes.Subresources(schema) {
+		if !slice.ContainsString(verbs, "create") {
+			continue
+		}
+		if schema.ActionHandlers == nil {
+			schema.ActionHandlers = map[string]http.Handler{}
+		}
+		if schema.ResourceActions == nil {
+			schema.ResourceActions = map[string]schemas.Action{}
+		}
+		schema.ActionHandlers[name] = NewSubresourceAction(clientGetter)
+		schema.ResourceActions[name] = schemas.Action{}
+	}
+}
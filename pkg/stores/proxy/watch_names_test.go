@@ -0,0 +1,276 @@
+package proxy
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/summary"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// watchClient plays the events of each field selector on the first watch, later watches
+// stay open until the request is done
+type watchClient struct {
+	dynamic.ResourceInterface
+
+	sync.Mutex
+	events    map[string][]watch.Event
+	selectors []string
+}
+
+func (c *watchClient) List(context.Context, metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+	list.SetResourceVersion("10")
+	return list, nil
+}
+
+func (c *watchClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	c.Lock()
+	events, first := c.events[opts.FieldSelector]
+	delete(c.events, opts.FieldSelector)
+	if first {
+		c.selectors = append(c.selectors, opts.FieldSelector)
+	}
+	c.Unlock()
+
+	w := watch.NewFakeWithChanSize(len(events), false)
+	for _, event := range events {
+		w.Action(event.Type, event.Object)
+	}
+	go func() {
+		<-ctx.Done()
+		w.Stop()
+	}()
+	return w, nil
+}
+
+type watchClientGetter struct {
+	ClientGetter
+	client dynamic.ResourceInterface
+}
+
+func (w watchClientGetter) TableAdminClientForWatch(*types.APIRequest, *types.APISchema, string) (dynamic.ResourceInterface, error) {
+	return w.client, nil
+}
+
+type countingNotifier struct {
+	subscriptions int32
+}
+
+func (c *countingNotifier) OnInboundRelationshipChange(ctx context.Context, _ *types.APISchema, _ string) <-chan *summary.Relationship {
+	atomic.AddInt32(&c.subscriptions, 1)
+	result := make(chan *summary.Relationship)
+	go func() {
+		<-ctx.Done()
+		close(result)
+	}()
+	return result
+}
+
+func pod(name, revision string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetName(name)
+	obj.SetResourceVersion(revision)
+	return obj
+}
+
+func bookmark(revision string) watch.Event {
+	return watch.Event{Type: watch.Bookmark, Object: pod("", revision)}
+}
+
+func expired() watch.Event {
+	status := apierrors.NewResourceExpired("too old").Status()
+	return watch.Event{Type: watch.Error, Object: &status}
+}
+
+func TestWatchNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		names         []string
+		events        map[string][]watch.Event
+		wantSelectors []string
+		want          []string
+	}{
+		{
+			name:  "a watch per name merges bookmarks and syncs",
+			names: []string{"a", "b"},
+			events: map[string][]watch.Event{
+				"metadata.name=a": {{Type: watch.Added, Object: pod("a", "2")}, bookmark("3"), expired()},
+				"metadata.name=b": {{Type: watch.Added, Object: pod("b", "4")}, bookmark("5"), expired()},
+			},
+			wantSelectors: []string{"metadata.name=a", "metadata.name=b"},
+			want:          []string{"resource.create a", "resource.create b", "resource.sync 10"},
+		},
+		{
+			name:  "many names share one filtered watch",
+			names: []string{"a", "b", "c", "d", "e", "f"},
+			events: map[string][]watch.Event{
+				"": {{Type: watch.Added, Object: pod("a", "2")}, {Type: watch.Added, Object: pod("other", "3")}, bookmark("4")},
+			},
+			wantSelectors: []string{""},
+			want:          []string{"resource.bookmark 4", "resource.create a"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &watchClient{events: test.events}
+			notifier := &countingNotifier{}
+			s := &Store{
+				clientGetter: watchClientGetter{client: client},
+				notifier:     notifier,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			apiOp := listRequest("")
+			apiOp = apiOp.WithContext(ctx)
+			apiSchema := &types.APISchema{Schema: &schemas.Schema{ID: "pod"}}
+			apiSchema.Attributes = map[string]interface{}{"version": "v1", "kind": "Pod"}
+
+			c, err := s.WatchNames(apiOp, apiSchema, types.WatchRequest{Revision: "1"}, sets.NewString(test.names...))
+			assert.NoError(t, err)
+
+			var got []string
+			timeout := time.After(5 * time.Second)
+			for len(got) < len(test.want) {
+				select {
+				case event := <-c:
+					got = append(got, describe(event))
+				case <-timeout:
+					t.Fatalf("timed out, got %v", got)
+				}
+			}
+
+			// nothing else must follow once the scripted events are played
+			select {
+			case event := <-c:
+				got = append(got, describe(event))
+			case <-time.After(100 * time.Millisecond):
+			}
+			cancel()
+			for event := range c {
+				got = append(got, describe(event))
+			}
+
+			sort.Strings(got)
+			sort.Strings(client.selectors)
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, test.wantSelectors, client.selectors)
+			assert.Equal(t, int32(1), atomic.LoadInt32(&notifier.subscriptions))
+		})
+	}
+}
+
+func describe(event types.APIEvent) string {
+	if event.Object.Object != nil {
+		return event.Name + " " + event.Object.Name()
+	}
+	return event.Name + " " + event.Revision
+}
+
+// restartClient closes the first watch after playing its events, later watches stay open
+// until the request is done
+type restartClient struct {
+	watchClient
+	revisions []string
+}
+
+func (c *restartClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	c.Lock()
+	c.revisions = append(c.revisions, opts.ResourceVersion)
+	first := len(c.revisions) == 1
+	events := c.events[opts.FieldSelector]
+	delete(c.events, opts.FieldSelector)
+	c.Unlock()
+
+	if !first {
+		return c.watchClient.Watch(ctx, opts)
+	}
+	w := watch.NewFakeWithChanSize(len(events), false)
+	for _, event := range events {
+		w.Action(event.Type, event.Object)
+	}
+	w.Stop()
+	return w, nil
+}
+
+func TestWatchRestart(t *testing.T) {
+	tests := []struct {
+		name          string
+		revision      string
+		events        []watch.Event
+		want          []string
+		wantRevisions []string
+	}{
+		{
+			name:          "resumes from the last event",
+			revision:      "-1",
+			events:        []watch.Event{{Type: watch.Added, Object: pod("a", "2")}},
+			want:          []string{"resource.create a"},
+			wantRevisions: []string{"", "2"},
+		},
+		{
+			name:          "resumes from a bookmark",
+			revision:      "1",
+			events:        []watch.Event{bookmark("3")},
+			want:          []string{"resource.bookmark 3"},
+			wantRevisions: []string{"1", "3"},
+		},
+		{
+			name:          "syncs without a revision",
+			revision:      "-1",
+			want:          []string{"resource.sync 10"},
+			wantRevisions: []string{"", "10"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &restartClient{watchClient: watchClient{events: map[string][]watch.Event{"": test.events}}}
+			s := &Store{}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			apiOp := listRequest("").WithContext(ctx)
+			apiSchema := &types.APISchema{Schema: &schemas.Schema{ID: "pod"}}
+
+			c := s.watch(apiOp, apiSchema, types.WatchRequest{Revision: test.revision}, client)
+
+			var got []string
+			timeout := time.After(5 * time.Second)
+			for len(got) < len(test.want) {
+				select {
+				case event := <-c:
+					got = append(got, describe(event))
+				case <-timeout:
+					t.Fatalf("timed out, got %v", got)
+				}
+			}
+			assert.Eventually(t, func() bool {
+				client.Lock()
+				defer client.Unlock()
+				return len(client.revisions) == len(test.wantRevisions)
+			}, 5*time.Second, 10*time.Millisecond)
+
+			select {
+			case event := <-c:
+				t.Fatalf("unexpected event %s", describe(event))
+			case <-time.After(100 * time.Millisecond):
+			}
+			assert.Equal(t, test.want, got)
+			client.Lock()
+			assert.Equal(t, test.wantRevisions, client.revisions)
+			client.Unlock()
+		})
+	}
+}
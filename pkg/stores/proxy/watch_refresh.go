@@ -20,24 +20,14 @@ func (w *WatchRefresh) Watch(apiOp *types.APIRequest, schema *types.APISchema, w
 		return w.Store.Watch(apiOp, schema, wr)
 	}
 
-	as := w.asl.AccessFor(user)
 	ctx, cancel := context.WithCancel(apiOp.Context())
 	apiOp = apiOp.WithContext(ctx)
 
+	changes := accesscontrol.WatchAccess(ctx, w.asl, user, 30*time.Second)
 	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(30 * time.Second):
-			}
-
-			newAs := w.asl.AccessFor(user)
-			if as.ID != newAs.ID {
-				// RBAC changed
-				cancel()
-				return
-			}
+		if _, ok := <-changes; ok {
+			// RBAC changed
+			cancel()
 		}
 	}()
 
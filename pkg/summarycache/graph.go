@@ -0,0 +1,271 @@
+package summarycache
+
+import (
+	"strings"
+
+	"github.com/rancher/steve/pkg/schema/converter"
+	"github.com/rancher/wrangler/pkg/summary"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type GraphNode struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	Depth         int    `json:"depth"`
+	State         string `json:"state,omitempty"`
+	Message       string `json:"message,omitempty"`
+	Error         bool   `json:"error,omitempty"`
+	Transitioning bool   `json:"transitioning,omitempty"`
+	// Health is the rolled up health of the pods of the node, if health rollup is enabled
+	Health map[string]interface{} `json:"health,omitempty"`
+}
+
+type GraphEdge struct {
+	FromID   string `json:"fromId"`
+	FromType string `json:"fromType"`
+	ToID     string `json:"toId"`
+	ToType   string `json:"toType"`
+	Rel      string `json:"rel,omitempty"`
+}
+
+type Graph struct {
+	Nodes     []GraphNode
+	Edges     []GraphEdge
+	Truncated bool
+}
+
+// CanGet is whether an object may be added to a graph
+type CanGet func(gvk runtimeschema.GroupVersionKind, namespace, name string) bool
+
+type graphRef struct {
+	gvk       runtimeschema.GroupVersionKind
+	namespace string
+	name      string
+}
+
+func (r graphRef) key() string {
+	return toKeyFrom(r.namespace, r.name, r.gvk)
+}
+
+func (r graphRef) id() string {
+	if r.namespace == "" {
+		return r.name
+	}
+	return r.namespace + "/" + r.name
+}
+
+type graphEdge struct {
+	from, to graphRef
+	rel      string
+}
+
+// Graph walks the relationships of an object in both directions up to depth hops. Objects
+// outside of the namespace of a namespaced root are not walked further so shared objects
+// such as nodes do not pull in the rest of the cluster. Objects canGet refuses are left out
+// along with their edges and are not walked through. The graph is read from the summaries
+// this cache keeps, the cluster cache is not called.
+func (s *SummaryCache) Graph(gvk runtimeschema.GroupVersionKind, namespace, name string, depth, maxNodes int, canGet CanGet) *Graph {
+	var (
+		root   = graphRef{gvk: gvk, namespace: namespace, name: name}
+		result = &Graph{}
+		depths = map[string]int{}
+		edges  = map[string]bool{}
+		queue  []graphRef
+	)
+
+	addNode := func(ref graphRef, d int) bool {
+		if _, ok := depths[ref.key()]; ok {
+			return true
+		}
+		if len(result.Nodes) >= maxNodes {
+			result.Truncated = true
+			return false
+		}
+		summarized, ok := s.summarized(ref)
+		if !ok {
+			return false
+		}
+		if d > 0 && !canGet(ref.gvk, ref.namespace, ref.name) {
+			return false
+		}
+		depths[ref.key()] = d
+		result.Nodes = append(result.Nodes, s.toGraphNode(ref, summarized, d))
+		queue = append(queue, ref)
+		return true
+	}
+
+	if !addNode(root, 0) {
+		return result
+	}
+
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+
+		d := depths[ref.key()]
+		if d >= depth || (d > 0 && root.namespace != "" && ref.namespace != root.namespace) {
+			continue
+		}
+
+		for _, edge := range s.graphEdges(ref) {
+			other := edge.to
+			if other.key() == ref.key() {
+				other = edge.from
+			}
+			if !addNode(other, d+1) {
+				continue
+			}
+
+			key := edge.from.key() + ">" + edge.to.key() + ">" + edge.rel
+			if edges[key] {
+				continue
+			}
+			edges[key] = true
+			result.Edges = append(result.Edges, GraphEdge{
+				FromID:   edge.from.id(),
+				FromType: converter.GVKToSchemaID(edge.from.gvk),
+				ToID:     edge.to.id(),
+				ToType:   converter.GVKToSchemaID(edge.to.gvk),
+				Rel:      edge.rel,
+			})
+		}
+	}
+
+	return result
+}
+
+// toGraphNode returns the node of an object, a degraded or progressing rolled up health
+// marks the node in error or transitioning even if the object itself is not
+func (s *SummaryCache) toGraphNode(ref graphRef, summarized *summary.SummarizedObject, depth int) GraphNode {
+	node := GraphNode{
+		ID:            ref.id(),
+		Type:          converter.GVKToSchemaID(ref.gvk),
+		Depth:         depth,
+		State:         summarized.State,
+		Message:       strings.Join(summarized.Message, "; "),
+		Error:         summarized.Error,
+		Transitioning: summarized.Transitioning,
+	}
+
+	health := s.rolledUpHealth(ref.gvk, ref.key())
+	if health == nil {
+		return node
+	}
+	node.Health = health.ToMap()
+	switch health.State {
+	case HealthDegraded:
+		node.Error = true
+	case HealthProgressing:
+		node.Transitioning = true
+	default:
+		return node
+	}
+	if node.Message == "" {
+		node.Message = health.Message
+	} else {
+		node.Message += "; " + health.Message
+	}
+	return node
+}
+
+func (s *SummaryCache) summarized(ref graphRef) (*summary.SummarizedObject, bool) {
+	obj, ok := s.cache.Get(ref.key())
+	if !ok {
+		return nil, false
+	}
+	summarized, ok := obj.(*summary.SummarizedObject)
+	return summarized, ok
+}
+
+// graphEdges returns the relationships of the object and the relationships other objects
+// have to it, by name or by a selector matching its labels
+func (s *SummaryCache) graphEdges(ref graphRef) (result []graphEdge) {
+	summarized, ok := s.summarized(ref)
+	if !ok {
+		return nil
+	}
+
+	for _, rel := range summarized.Relationships {
+		gvk := runtimeschema.FromAPIVersionAndKind(rel.APIVersion, rel.Kind)
+		ns := s.resolveNamespace(ref.namespace, rel.Namespace, gvk)
+
+		var targets []graphRef
+		if rel.Selector != nil {
+			targets = s.selected(gvk, ns, rel.Selector)
+		} else {
+			targets = []graphRef{{gvk: gvk, namespace: ns, name: rel.Name}}
+		}
+
+		for _, target := range targets {
+			result = append(result, toGraphEdge(ref, target, rel))
+		}
+	}
+
+	relObjs, err := s.cache.ByIndex(relationshipIndex, ref.key())
+	if err == nil {
+		for _, relObj := range relObjs {
+			other := relObj.(*summary.SummarizedObject)
+			for _, rel := range other.Relationships {
+				if rel.Selector == nil && s.refersTo(summarized, &rel) {
+					result = append(result, toGraphEdge(summarizedRef(other), ref, rel))
+				}
+			}
+		}
+	}
+
+	// selector relationships are indexed without a name
+	relObjs, err = s.cache.ByIndex(relationshipIndex, toKeyFrom(ref.namespace, "", ref.gvk))
+	if err == nil {
+		for _, relObj := range relObjs {
+			other := relObj.(*summary.SummarizedObject)
+			for _, rel := range other.Relationships {
+				if rel.Selector == nil || rel.APIVersion != summarized.APIVersion || rel.Kind != summarized.Kind {
+					continue
+				}
+				if selector, err := metav1.LabelSelectorAsSelector(rel.Selector); err == nil && selector.Matches(labels.Set(summarized.Labels)) {
+					result = append(result, toGraphEdge(summarizedRef(other), ref, rel))
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// selected returns the objects of the kind in the namespace that match the selector
+func (s *SummaryCache) selected(gvk runtimeschema.GroupVersionKind, namespace string, labelSelector *metav1.LabelSelector) (result []graphRef) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil || selector.Empty() {
+		return nil
+	}
+	objs, err := s.cache.ByIndex(kindIndex, toKeyFrom(namespace, "", gvk))
+	if err != nil {
+		return nil
+	}
+	for _, obj := range objs {
+		summarized := obj.(*summary.SummarizedObject)
+		if selector.Matches(labels.Set(summarized.Labels)) {
+			result = append(result, graphRef{gvk: gvk, namespace: namespace, name: summarized.Name})
+		}
+	}
+	return result
+}
+
+// toGraphEdge orients a relationship that source holds to target, inbound relationships
+// point from the target to the source
+func toGraphEdge(source, target graphRef, rel summary.Relationship) graphEdge {
+	if rel.Inbound {
+		return graphEdge{from: target, to: source, rel: rel.Type}
+	}
+	return graphEdge{from: source, to: target, rel: rel.Type}
+}
+
+func summarizedRef(summarized *summary.SummarizedObject) graphRef {
+	return graphRef{
+		gvk:       summarized.GroupVersionKind(),
+		namespace: summarized.Namespace,
+		name:      summarized.Name,
+	}
+}
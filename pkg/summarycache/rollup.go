@@ -0,0 +1,346 @@
+package summarycache
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rancher/wrangler/pkg/summary"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	HealthHealthy     = "healthy"
+	HealthProgressing = "progressing"
+	HealthDegraded    = "degraded"
+
+	maxOwnerDepth = 5
+)
+
+var (
+	podGVK = runtimeschema.GroupVersionKind{
+		Version: "v1",
+		Kind:    "Pod",
+	}
+	namespaceGVK = runtimeschema.GroupVersionKind{
+		Version: "v1",
+		Kind:    "Namespace",
+	}
+	// rollupKinds have the health of the pods they own or select rolled up
+	rollupKinds = map[runtimeschema.GroupKind]bool{
+		{Group: "apps", Kind: "Deployment"}:  true,
+		{Group: "apps", Kind: "StatefulSet"}: true,
+		{Group: "apps", Kind: "DaemonSet"}:   true,
+		{Group: "apps", Kind: "ReplicaSet"}:  true,
+		{Group: "batch", Kind: "Job"}:        true,
+		{Group: "batch", Kind: "CronJob"}:    true,
+		{Kind: "ReplicationController"}:      true,
+		{Kind: "Service"}:                    true,
+		{Kind: namespaceGVK.Kind}:            true,
+	}
+)
+
+type Health struct {
+	State         string
+	Message       string
+	Total         int
+	Error         int
+	Transitioning int
+}
+
+// ToMap returns the health as it is put in metadata.state
+func (h *Health) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"state":         h.State,
+		"message":       h.Message,
+		"total":         int64(h.Total),
+		"error":         int64(h.Error),
+		"transitioning": int64(h.Transitioning),
+	}
+}
+
+type podHealth struct {
+	error         bool
+	transitioning bool
+}
+
+// rollup tracks which parents every pod counts towards and the health of the pods of
+// every parent, it is updated from the cluster cache events the summary cache receives
+type rollup struct {
+	sync.Mutex
+
+	parents  map[string][]graphRef
+	children map[string]map[string]podHealth
+}
+
+// EnableHealthRollup rolls the health of pods up to the workloads, services and namespaces
+// they belong to. It must be called before Start.
+func (s *SummaryCache) EnableHealthRollup() {
+	s.rollup = &rollup{
+		parents:  map[string][]graphRef{},
+		children: map[string]map[string]podHealth{},
+	}
+}
+
+// Health returns the rolled up health of the pods of obj, nil if the rollup is disabled or
+// obj is not a kind that pods roll up to
+func (s *SummaryCache) Health(obj runtime.Object) *Health {
+	return s.rolledUpHealth(obj.GetObjectKind().GroupVersionKind(), toKey(obj))
+}
+
+func (s *SummaryCache) rolledUpHealth(gvk runtimeschema.GroupVersionKind, key string) *Health {
+	if s.rollup == nil || !rollupKinds[gvk.GroupKind()] {
+		return nil
+	}
+
+	s.rollup.Lock()
+	defer s.rollup.Unlock()
+	return s.rollup.health(key)
+}
+
+func (r *rollup) health(parentKey string) *Health {
+	result := &Health{}
+	for _, pod := range r.children[parentKey] {
+		result.Total++
+		if pod.error {
+			result.Error++
+		}
+		if pod.transitioning {
+			result.Transitioning++
+		}
+	}
+
+	switch {
+	case result.Error > 0:
+		result.State = HealthDegraded
+		result.Message = fmt.Sprintf("%s: %d/%d pods in error", result.State, result.Error, result.Total)
+	case result.Transitioning > 0:
+		result.State = HealthProgressing
+		result.Message = fmt.Sprintf("%s: %d/%d pods in progress", result.State, result.Transitioning, result.Total)
+	default:
+		result.State = HealthHealthy
+	}
+	return result
+}
+
+// updateRollup is called with every object that is added, changed or removed
+func (s *SummaryCache) updateRollup(obj *summary.SummarizedObject, rels []*summary.Relationship, oldRels []*summary.Relationship, removed bool) {
+	if s.rollup == nil {
+		return
+	}
+
+	gvk := obj.GroupVersionKind()
+	if gvk == podGVK {
+		if removed {
+			s.setPod(toKey(obj), nil, podHealth{})
+		} else {
+			s.setPod(toKey(obj), s.podParents(obj), toPodHealth(obj))
+		}
+		return
+	}
+
+	if !rollupKinds[gvk.GroupKind()] || gvk.GroupKind() == namespaceGVK.GroupKind() || obj.Namespace == "" {
+		return
+	}
+	if !removed && oldRels != nil && relsEqual(rels, oldRels) {
+		return
+	}
+
+	// the owners or selector of a parent changed so the pods that count towards it, and the
+	// pods it selects now, may count towards other parents
+	for _, pod := range s.affectedPods(obj) {
+		s.setPod(toKey(pod), s.podParents(pod), toPodHealth(pod))
+	}
+}
+
+// affectedPods returns the pods that count towards parent and, for a service, the pods it
+// selects. A pod counts towards its owners even before they are seen so the pods a parent
+// owns are always among the pods that count towards it.
+func (s *SummaryCache) affectedPods(parent *summary.SummarizedObject) (result []*summary.SummarizedObject) {
+	seen := map[string]bool{}
+	add := func(pod *summary.SummarizedObject) {
+		if key := toKey(pod); !seen[key] {
+			seen[key] = true
+			result = append(result, pod)
+		}
+	}
+
+	s.rollup.Lock()
+	var counted []string
+	for podKey := range s.rollup.children[toKey(parent)] {
+		counted = append(counted, podKey)
+	}
+	s.rollup.Unlock()
+	for _, podKey := range counted {
+		if pod, ok := s.cache.Get(podKey); ok {
+			add(pod.(*summary.SummarizedObject))
+		}
+	}
+
+	if parent.Kind != "Service" {
+		return result
+	}
+	for _, rel := range parent.Relationships {
+		if rel.Selector == nil || rel.Kind != podGVK.Kind {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(rel.Selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		pods, err := s.cache.ByIndex(kindIndex, toKeyFrom(parent.Namespace, "", podGVK))
+		if err != nil {
+			continue
+		}
+		for _, pod := range pods {
+			summarized := pod.(*summary.SummarizedObject)
+			if selector.Matches(labels.Set(summarized.Labels)) {
+				add(summarized)
+			}
+		}
+	}
+	return result
+}
+
+func toPodHealth(pod *summary.SummarizedObject) podHealth {
+	return podHealth{
+		error:         pod.Error,
+		transitioning: pod.Transitioning,
+	}
+}
+
+// setPod records the parents and health of a pod and notifies the watchers of every parent
+// whose health changed
+func (s *SummaryCache) setPod(podKey string, parents []graphRef, health podHealth) {
+	s.rollup.Lock()
+
+	affected := map[string]graphRef{}
+	for _, parent := range s.rollup.parents[podKey] {
+		affected[parent.key()] = parent
+	}
+	for _, parent := range parents {
+		affected[parent.key()] = parent
+	}
+
+	before := map[string]Health{}
+	for key := range affected {
+		before[key] = *s.rollup.health(key)
+	}
+
+	for _, parent := range s.rollup.parents[podKey] {
+		children := s.rollup.children[parent.key()]
+		delete(children, podKey)
+		if len(children) == 0 {
+			delete(s.rollup.children, parent.key())
+		}
+	}
+	if len(parents) == 0 {
+		delete(s.rollup.parents, podKey)
+	} else {
+		s.rollup.parents[podKey] = parents
+	}
+	for _, parent := range parents {
+		children := s.rollup.children[parent.key()]
+		if children == nil {
+			children = map[string]podHealth{}
+			s.rollup.children[parent.key()] = children
+		}
+		children[podKey] = health
+	}
+
+	var changed []graphRef
+	for key, parent := range affected {
+		if *s.rollup.health(key) != before[key] {
+			changed = append(changed, parent)
+		}
+	}
+
+	s.rollup.Unlock()
+
+	for _, parent := range changed {
+		apiVersion, kind := parent.gvk.ToAPIVersionAndKind()
+		s.notify(&summary.Relationship{
+			Name:       parent.name,
+			Namespace:  parent.namespace,
+			Kind:       kind,
+			APIVersion: apiVersion,
+		})
+	}
+}
+
+// podParents returns the objects the pod rolls up to, its owners and their owners, the
+// services selecting it and its namespace. It only reads the summaries this cache keeps as
+// it runs on the cluster cache event goroutine.
+func (s *SummaryCache) podParents(pod *summary.SummarizedObject) (result []graphRef) {
+	seen := map[string]bool{}
+	add := func(ref graphRef) {
+		if !seen[ref.key()] && rollupKinds[ref.gvk.GroupKind()] {
+			seen[ref.key()] = true
+			result = append(result, ref)
+		}
+	}
+
+	owners := ownerRefs(pod)
+	for depth := 0; depth < maxOwnerDepth && len(owners) > 0; depth++ {
+		var next []graphRef
+		for _, owner := range owners {
+			add(owner)
+			if summarized, ok := s.summarized(owner); ok {
+				next = append(next, ownerRefs(summarized)...)
+			}
+		}
+		owners = next
+	}
+
+	relObjs, err := s.cache.ByIndex(relationshipIndex, toKeyFrom(pod.Namespace, "", podGVK))
+	if err == nil {
+		for _, relObj := range relObjs {
+			other := relObj.(*summary.SummarizedObject)
+			if other.Kind != "Service" {
+				continue
+			}
+			for _, rel := range other.Relationships {
+				if rel.Selector == nil || rel.Kind != podGVK.Kind {
+					continue
+				}
+				if selector, err := metav1.LabelSelectorAsSelector(rel.Selector); err == nil && !selector.Empty() && selector.Matches(labels.Set(pod.Labels)) {
+					add(summarizedRef(other))
+				}
+			}
+		}
+	}
+
+	if pod.Namespace != "" {
+		add(graphRef{gvk: namespaceGVK, name: pod.Namespace})
+	}
+
+	return result
+}
+
+func ownerRefs(obj *summary.SummarizedObject) (result []graphRef) {
+	for _, rel := range obj.Relationships {
+		if rel.Type != "owner" || !rel.Inbound {
+			continue
+		}
+		result = append(result, graphRef{
+			gvk:       runtimeschema.FromAPIVersionAndKind(rel.APIVersion, rel.Kind),
+			namespace: obj.Namespace,
+			name:      rel.Name,
+		})
+	}
+	return result
+}
+
+func relsEqual(left, right []*summary.Relationship) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		if !relEquals(left[i], right[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,262 @@
+package summarycache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/attributes"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/steve/pkg/schema/converter"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/schemas"
+	"github.com/rancher/wrangler/pkg/summary"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	deploymentGVK = runtimeschema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	replicaSetGVK = runtimeschema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+	serviceGVK    = runtimeschema.GroupVersionKind{Version: "v1", Kind: "Service"}
+)
+
+func newRollupCache(t *testing.T) (*SummaryCache, *fakeClusterCache) {
+	collection := schema.NewCollection(context.Background(), types.EmptyAPISchemas(), nil)
+	apiSchemas := map[string]*types.APISchema{}
+	for gvk, namespaced := range map[runtimeschema.GroupVersionKind]bool{
+		podGVK:        true,
+		replicaSetGVK: true,
+		deploymentGVK: true,
+		serviceGVK:    true,
+		namespaceGVK:  false,
+	} {
+		apiSchema := &types.APISchema{
+			Schema: &schemas.Schema{
+				ID:         converter.GVKToSchemaID(gvk),
+				Attributes: map[string]interface{}{},
+			},
+		}
+		attributes.SetGVK(apiSchema, gvk)
+		attributes.SetNamespaced(apiSchema, namespaced)
+		apiSchemas[apiSchema.ID] = apiSchema
+	}
+	collection.Reset(apiSchemas)
+
+	ccache := newFakeClusterCache()
+	s := New(collection, ccache)
+	s.EnableHealthRollup()
+	// pods report their state in status.result so the tests do not depend on the wrangler
+	// pod summarizer
+	s.AddSummarizer(podGVK.GroupKind(), func(obj data.Object, conditions []summary.Condition, result summary.Summary) summary.Summary {
+		result.State = obj.String("status", "result")
+		result.Error = result.State == "Failed"
+		result.Transitioning = result.State == "Pending"
+		return result
+	})
+	return s, ccache
+}
+
+func object(gvk runtimeschema.GroupVersionKind, namespace, name string, owner *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	if owner != nil {
+		controller := true
+		obj.Object["metadata"].(map[string]interface{})["ownerReferences"] = []interface{}{
+			map[string]interface{}{
+				"apiVersion": owner.GetAPIVersion(),
+				"kind":       owner.GetKind(),
+				"name":       owner.GetName(),
+				"uid":        owner.GetName(),
+				"controller": controller,
+			},
+		}
+	}
+	return obj
+}
+
+func rolloutPod(name string, owner *unstructured.Unstructured, result string, labels map[string]string) *unstructured.Unstructured {
+	pod := object(podGVK, "default", name, owner)
+	pod.SetLabels(labels)
+	pod.Object["status"] = map[string]interface{}{"result": result}
+	return pod
+}
+
+func service(app string) *unstructured.Unstructured {
+	svc := object(serviceGVK, "default", "web", nil)
+	svc.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{"app": app},
+	}
+	return svc
+}
+
+type wantHealth struct {
+	obj           runtime.Object
+	state         string
+	total         int
+	errors        int
+	transitioning int
+}
+
+func TestHealthRollup(t *testing.T) {
+	var (
+		deployment = object(deploymentGVK, "default", "web", nil)
+		replicaSet = object(replicaSetGVK, "default", "web-1", deployment)
+		namespace  = object(namespaceGVK, "", "default", nil)
+		web        = map[string]string{"app": "web"}
+		failed     = rolloutPod("web-1-a", replicaSet, "Failed", web)
+		running    = rolloutPod("web-1-b", replicaSet, "Running", web)
+		pending    = rolloutPod("web-1-c", replicaSet, "Pending", web)
+		other      = rolloutPod("other", nil, "Failed", map[string]string{"app": "other"})
+	)
+
+	tests := []struct {
+		name  string
+		steps func(s *SummaryCache)
+		want  []wantHealth
+	}{
+		{
+			name: "owners added first",
+			steps: func(s *SummaryCache) {
+				s.Add(deployment)
+				s.Add(replicaSet)
+				s.Add(failed)
+				s.Add(running)
+			},
+			want: []wantHealth{
+				{obj: deployment, state: HealthDegraded, total: 2, errors: 1},
+				{obj: replicaSet, state: HealthDegraded, total: 2, errors: 1},
+				{obj: namespace, state: HealthDegraded, total: 2, errors: 1},
+			},
+		},
+		{
+			name: "pods added first",
+			steps: func(s *SummaryCache) {
+				s.Add(running)
+				s.Add(pending)
+				s.Add(replicaSet)
+				s.Add(deployment)
+			},
+			want: []wantHealth{
+				{obj: deployment, state: HealthProgressing, total: 2, transitioning: 1},
+				{obj: replicaSet, state: HealthProgressing, total: 2, transitioning: 1},
+			},
+		},
+		{
+			name: "pod recovers",
+			steps: func(s *SummaryCache) {
+				s.Add(deployment)
+				s.Add(replicaSet)
+				s.Add(failed)
+				s.Change(rolloutPod(failed.GetName(), replicaSet, "Running", web), failed)
+			},
+			want: []wantHealth{
+				{obj: deployment, state: HealthHealthy, total: 1},
+			},
+		},
+		{
+			name: "pod removed",
+			steps: func(s *SummaryCache) {
+				s.Add(deployment)
+				s.Add(replicaSet)
+				s.Add(failed)
+				s.Add(running)
+				s.Remove(failed)
+			},
+			want: []wantHealth{
+				{obj: deployment, state: HealthHealthy, total: 1},
+			},
+		},
+		{
+			name: "service selects pods",
+			steps: func(s *SummaryCache) {
+				s.Add(failed)
+				s.Add(other)
+				s.Add(service("web"))
+			},
+			want: []wantHealth{
+				{obj: service("web"), state: HealthDegraded, total: 1, errors: 1},
+				{obj: namespace, state: HealthDegraded, total: 2, errors: 2},
+			},
+		},
+		{
+			name: "service selector changed",
+			steps: func(s *SummaryCache) {
+				s.Add(running)
+				s.Add(other)
+				s.Add(service("web"))
+				s.Change(service("other"), service("web"))
+			},
+			want: []wantHealth{
+				{obj: service("other"), state: HealthDegraded, total: 1, errors: 1},
+			},
+		},
+		{
+			name: "unowned pod",
+			steps: func(s *SummaryCache) {
+				s.Add(deployment)
+				s.Add(other)
+			},
+			want: []wantHealth{
+				{obj: deployment, state: HealthHealthy},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, ccache := newRollupCache(t)
+			test.steps(s)
+
+			for _, want := range test.want {
+				health := s.Health(want.obj)
+				if !assert.NotNil(t, health, toKey(want.obj)) {
+					continue
+				}
+				assert.Equal(t, want.state, health.State, toKey(want.obj))
+				assert.Equal(t, want.total, health.Total, toKey(want.obj))
+				assert.Equal(t, want.errors, health.Error, toKey(want.obj))
+				assert.Equal(t, want.transitioning, health.Transitioning, toKey(want.obj))
+			}
+			assert.Empty(t, ccache.gets, "the rollup must not read the cluster cache")
+		})
+	}
+}
+
+func TestHealthRollupNotifies(t *testing.T) {
+	s, _ := newRollupCache(t)
+	deployment := object(deploymentGVK, "default", "web", nil)
+	replicaSet := object(replicaSetGVK, "default", "web-1", deployment)
+	running := rolloutPod("web-1-a", replicaSet, "Running", nil)
+	s.Add(deployment)
+	s.Add(replicaSet)
+	s.Add(running)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := s.subs.subscribe(ctx, subscriptionKey{
+		apiVersion: "apps/v1",
+		kind:       "Deployment",
+		namespace:  "default",
+	})
+
+	s.Change(rolloutPod(running.GetName(), replicaSet, "Failed", nil), running)
+	select {
+	case rel := <-changes:
+		assert.Equal(t, "web", rel.Name)
+	case <-time.After(time.Second):
+		t.Fatal("no change for the deployment")
+	}
+
+	// the health of the deployment does not change so there is nothing to notify
+	s.Change(rolloutPod(running.GetName(), replicaSet, "Failed", map[string]string{"a": "b"}), running)
+	select {
+	case rel := <-changes:
+		t.Fatalf("unexpected change for %s", rel.Name)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,106 @@
+package summarycache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/wrangler/pkg/summary"
+	"github.com/sirupsen/logrus"
+)
+
+const subscriptionBuffer = 100
+
+type subscriptionKey struct {
+	apiVersion string
+	kind       string
+	namespace  string
+}
+
+type subscription struct {
+	c       chan *summary.Relationship
+	dropped uint64
+}
+
+// subscriptions is the registry of relationship change subscribers indexed by the kind and
+// namespace they watch. Delivery never blocks, a change is dropped for a subscriber whose
+// buffer is full so a slow watcher can not stall the summary cache.
+type subscriptions struct {
+	sync.RWMutex
+
+	nextID  int
+	byKey   map[subscriptionKey]map[int]*subscription
+	dropped uint64
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{
+		byKey: map[subscriptionKey]map[int]*subscription{},
+	}
+}
+
+// subscribe returns a channel of the changes to relationships to objects of the kind in
+// the namespace. An empty namespace receives the changes to cluster scoped objects and
+// accesscontrol.All the changes of every namespace. The channel is closed when ctx is done.
+func (s *subscriptions) subscribe(ctx context.Context, key subscriptionKey) <-chan *summary.Relationship {
+	sub := &subscription{
+		c: make(chan *summary.Relationship, subscriptionBuffer),
+	}
+
+	s.Lock()
+	id := s.nextID
+	s.nextID++
+	if s.byKey[key] == nil {
+		s.byKey[key] = map[int]*subscription{}
+	}
+	s.byKey[key][id] = sub
+	s.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.Lock()
+		defer s.Unlock()
+		delete(s.byKey[key], id)
+		if len(s.byKey[key]) == 0 {
+			delete(s.byKey, key)
+		}
+		close(sub.c)
+		if dropped := atomic.LoadUint64(&sub.dropped); dropped > 0 {
+			logrus.Debugf("dropped %d relationship changes for a slow watcher of %s %s", dropped, key.apiVersion, key.kind)
+		}
+	}()
+
+	return sub.c
+}
+
+func (s *subscriptions) publish(rel *summary.Relationship) {
+	key := subscriptionKey{
+		apiVersion: rel.APIVersion,
+		kind:       rel.Kind,
+		namespace:  rel.Namespace,
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	s.deliver(key, rel)
+	key.namespace = accesscontrol.All
+	s.deliver(key, rel)
+}
+
+func (s *subscriptions) deliver(key subscriptionKey, rel *summary.Relationship) {
+	for _, sub := range s.byKey[key] {
+		select {
+		case sub.c <- rel:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// Dropped is the number of changes dropped for slow subscribers since start
+func (s *subscriptions) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
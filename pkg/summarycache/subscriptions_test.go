@@ -0,0 +1,57 @@
+package summarycache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rancher/steve/pkg/accesscontrol"
+	"github.com/rancher/wrangler/pkg/summary"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptions(t *testing.T) {
+	var (
+		namespaced = &summary.Relationship{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "a"}
+		otherNS    = &summary.Relationship{APIVersion: "v1", Kind: "Pod", Namespace: "other", Name: "b"}
+		cluster    = &summary.Relationship{APIVersion: "v1", Kind: "Pod", Name: "c"}
+		otherKind  = &summary.Relationship{APIVersion: "v1", Kind: "Service", Namespace: "default", Name: "d"}
+	)
+
+	tests := []struct {
+		name      string
+		namespace string
+		want      []string
+	}{
+		{name: "one namespace", namespace: "default", want: []string{"a"}},
+		{name: "cluster scoped", namespace: "", want: []string{"c"}},
+		{name: "every namespace", namespace: accesscontrol.All, want: []string{"a", "b", "c"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subs := newSubscriptions()
+			ctx, cancel := context.WithCancel(context.Background())
+			changes := subs.subscribe(ctx, subscriptionKey{apiVersion: "v1", kind: "Pod", namespace: test.namespace})
+
+			for _, rel := range []*summary.Relationship{namespaced, otherNS, cluster, otherKind} {
+				subs.publish(rel)
+			}
+			cancel()
+
+			got := []string{}
+			timeout := time.After(5 * time.Second)
+			for {
+				select {
+				case rel, ok := <-changes:
+					if !ok {
+						assert.Equal(t, test.want, got)
+						return
+					}
+					got = append(got, rel.Name)
+				case <-timeout:
+					t.Fatalf("timed out, got %v", got)
+				}
+			}
+		})
+	}
+}
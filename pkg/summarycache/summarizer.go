@@ -0,0 +1,66 @@
+package summarycache
+
+import (
+	"strings"
+
+	"github.com/rancher/steve/pkg/clustercache"
+	"github.com/rancher/wrangler/pkg/summary"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// AddSummarizer registers a summarizer for a kind, it takes precedence over a summarizer
+// of a schema template. The objects of the kind the cluster cache already holds are
+// listed again so they are summarized with it.
+func (s *SummaryCache) AddSummarizer(gk runtimeschema.GroupKind, summarizer summary.Summarizer) {
+	s.summarizersLock.Lock()
+	s.summarizers[gk] = summarizer
+	s.summarizersLock.Unlock()
+
+	if summarizing, ok := s.clusterCache.(clustercache.Summarizing); ok {
+		summarizing.Resummarize(gk)
+	}
+}
+
+func (s *SummaryCache) summarizer(gvk runtimeschema.GroupVersionKind) summary.Summarizer {
+	s.summarizersLock.RLock()
+	summarizer := s.summarizers[gvk.GroupKind()]
+	s.summarizersLock.RUnlock()
+	if summarizer != nil {
+		return summarizer
+	}
+	return s.schemas.Summarizer(gvk)
+}
+
+// summarize returns the summary of obj with the summarizer of its kind run after the
+// wrangler summarizers. The cluster cache summarizes the objects it lists with it, so
+// objects it already summarized are returned as they are.
+func (s *SummaryCache) summarize(obj runtime.Object) *summary.SummarizedObject {
+	summarized := summary.Summarized(obj)
+	if _, ok := obj.(*summary.SummarizedObject); ok || summarized.Kind == "" {
+		return summarized
+	}
+
+	summarizer := s.summarizer(summarized.GroupVersionKind())
+	if summarizer == nil {
+		return summarized
+	}
+
+	var data map[string]interface{}
+	if unstr, ok := obj.(*unstructured.Unstructured); ok {
+		data = unstr.Object
+	} else if converted, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err == nil {
+		data = converted
+	} else {
+		return summarized
+	}
+
+	result := summarizer(data, summary.GetUnstructuredConditions(data), summarized.Summary)
+	if result.State == "" {
+		result.State = "active"
+	}
+	result.State = strings.ToLower(result.State)
+	summarized.Summary = result
+	return summarized
+}
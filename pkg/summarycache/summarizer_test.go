@@ -0,0 +1,153 @@
+package summarycache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/clustercache"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/wrangler/pkg/data"
+	"github.com/rancher/wrangler/pkg/summary"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var widgetGK = runtimeschema.GroupKind{Group: "example.io", Kind: "Widget"}
+
+// fakeClusterCache holds objects by key and records what the summary cache asks of it
+type fakeClusterCache struct {
+	objects     map[string]runtime.Object
+	summarize   clustercache.Summarize
+	resummarize []runtimeschema.GroupKind
+	gets        int
+}
+
+func newFakeClusterCache(objs ...runtime.Object) *fakeClusterCache {
+	c := &fakeClusterCache{
+		objects: map[string]runtime.Object{},
+	}
+	for _, obj := range objs {
+		c.objects[toKey(obj)] = obj
+	}
+	return c
+}
+
+func (c *fakeClusterCache) Get(gvk runtimeschema.GroupVersionKind, namespace, name string) (interface{}, bool, error) {
+	c.gets++
+	obj, ok := c.objects[toKeyFrom(namespace, name, gvk)]
+	return obj, ok, nil
+}
+
+func (c *fakeClusterCache) List(gvk runtimeschema.GroupVersionKind) (result []interface{}) {
+	for _, obj := range c.objects {
+		if obj.GetObjectKind().GroupVersionKind() == gvk {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+func (c *fakeClusterCache) OnAdd(ctx context.Context, handler clustercache.Handler)          {}
+func (c *fakeClusterCache) OnRemove(ctx context.Context, handler clustercache.Handler)       {}
+func (c *fakeClusterCache) OnChange(ctx context.Context, handler clustercache.ChangeHandler) {}
+func (c *fakeClusterCache) OnSchemas(schemas *schema.Collection) error                       { return nil }
+
+func (c *fakeClusterCache) SetSummarize(summarize clustercache.Summarize) {
+	c.summarize = summarize
+}
+
+func (c *fakeClusterCache) Resummarize(gk runtimeschema.GroupKind) {
+	c.resummarize = append(c.resummarize, gk)
+}
+
+func widget(result string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.io/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"name":      "widget",
+			"namespace": "default",
+		},
+		"status": map[string]interface{}{
+			"result": result,
+		},
+	}}
+}
+
+// resultSummarizer sets the state from status.result, the wrangler summarizers do not know
+// the field, and marks the Failed result as an error
+func resultSummarizer(obj data.Object, conditions []summary.Condition, result summary.Summary) summary.Summary {
+	result.State = obj.String("status", "result")
+	result.Error = result.State == "Failed"
+	return result
+}
+
+func stateSummarizer(state string) summary.Summarizer {
+	return func(obj data.Object, conditions []summary.Condition, result summary.Summary) summary.Summary {
+		result.State = state
+		return result
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  summary.Summarizer
+		kind      summary.Summarizer
+		obj       runtime.Object
+		wantState string
+		wantError bool
+	}{
+		{name: "no summarizer", obj: widget("Failed"), wantState: "active"},
+		{name: "kind summarizer", kind: resultSummarizer, obj: widget("Failed"), wantState: "failed", wantError: true},
+		{name: "template summarizer", template: resultSummarizer, obj: widget("Running"), wantState: "running"},
+		{name: "kind summarizer wins", template: stateSummarizer("template"), kind: stateSummarizer("kind"), obj: widget("Running"), wantState: "kind"},
+		{name: "empty state", kind: stateSummarizer(""), obj: widget("Running"), wantState: "active"},
+		{
+			name: "already summarized",
+			kind: stateSummarizer("kind"),
+			obj: &summary.SummarizedObject{
+				Summary: summary.Summary{State: "summarized"},
+			},
+			wantState: "summarized",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schemas := schema.NewCollection(context.Background(), types.EmptyAPISchemas(), nil)
+			if test.template != nil {
+				schemas.AddTemplate(schema.Template{
+					Group:      widgetGK.Group,
+					Kind:       widgetGK.Kind,
+					Summarizer: test.template,
+				})
+			}
+			s := New(schemas, newFakeClusterCache())
+			if test.kind != nil {
+				s.AddSummarizer(widgetGK, test.kind)
+			}
+
+			summarized := s.summarize(test.obj)
+			assert.Equal(t, test.wantState, summarized.State)
+			assert.Equal(t, test.wantError, summarized.Error)
+		})
+	}
+}
+
+func TestAddSummarizer(t *testing.T) {
+	globalSummarizers := len(summary.Summarizers)
+	ccache := newFakeClusterCache()
+	s := New(schema.NewCollection(context.Background(), types.EmptyAPISchemas(), nil), ccache)
+
+	assert.Len(t, summary.Summarizers, globalSummarizers, "the wrangler summarizers must not be changed")
+	if assert.NotNil(t, ccache.summarize, "the cluster cache must summarize with the summary cache") {
+		assert.Equal(t, "active", ccache.summarize(widget("Running")).State)
+	}
+
+	s.AddSummarizer(widgetGK, resultSummarizer)
+	assert.Equal(t, []runtimeschema.GroupKind{widgetGK}, ccache.resummarize)
+	assert.Equal(t, "running", ccache.summarize(widget("Running")).State)
+}
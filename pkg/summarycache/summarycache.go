@@ -10,10 +10,12 @@ import (
 	"github.com/rancher/steve/pkg/clustercache"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/schema/converter"
+	"github.com/rancher/wrangler/pkg/data"
 	"github.com/rancher/wrangler/pkg/slice"
 	"github.com/rancher/wrangler/pkg/summary"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	runtimeschema "k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/cache"
@@ -21,10 +23,7 @@ import (
 
 const (
 	relationshipIndex = "relationshipIndex"
-)
-
-var (
-	cbID = 0
+	kindIndex         = "kindIndex"
 )
 
 type Relationship struct {
@@ -47,7 +46,12 @@ type SummaryCache struct {
 	cache        cache.ThreadSafeStore
 	schemas      *schema.Collection
 	clusterCache clustercache.ClusterCache
-	cbs          map[int]chan *summary.Relationship
+	subs         *subscriptions
+
+	summarizersLock sync.RWMutex
+	summarizers     map[runtimeschema.GroupKind]summary.Summarizer
+
+	rollup *rollup
 }
 
 func New(schemas *schema.Collection, clusterCache clustercache.ClusterCache) *SummaryCache {
@@ -56,49 +60,47 @@ func New(schemas *schema.Collection, clusterCache clustercache.ClusterCache) *Su
 		cache:        cache.NewThreadSafeStore(indexers, cache.Indices{}),
 		schemas:      schemas,
 		clusterCache: clusterCache,
-		cbs:          map[int]chan *summary.Relationship{},
+		subs:         newSubscriptions(),
+		summarizers:  map[runtimeschema.GroupKind]summary.Summarizer{},
 	}
 	indexers[relationshipIndex] = s.relationshipIndexer
+	indexers[kindIndex] = kindIndexer
+	if summarizing, ok := clusterCache.(clustercache.Summarizing); ok {
+		summarizing.SetSummarize(s.summarize)
+	}
 	return s
 }
 
+// Start handles the changes of the cluster cache. The informers of pods and of the kinds their
+// health is rolled up to are kept running, relationships to and from other kinds are only
+// known while their informers run.
 func (s *SummaryCache) Start(ctx context.Context) {
+	if extended, ok := s.clusterCache.(clustercache.Extended); ok {
+		extended.Keep(ctx, func(gvk runtimeschema.GroupVersionKind) bool {
+			return gvk == podGVK || rollupKinds[gvk.GroupKind()]
+		})
+	}
 	s.clusterCache.OnAdd(ctx, s.OnAdd)
 	s.clusterCache.OnRemove(ctx, s.OnRemove)
 	s.clusterCache.OnChange(ctx, s.OnChange)
 }
 
+// OnInboundRelationshipChange returns the changes to relationships to objects of the schema in
+// the namespace, or in every namespace if namespace is accesscontrol.All. An empty namespace
+// is for cluster scoped objects. Changes are dropped when the
+// channel is not read fast enough.
 func (s *SummaryCache) OnInboundRelationshipChange(ctx context.Context, schema *types.APISchema, namespace string) <-chan *summary.Relationship {
-	s.Lock()
-	defer s.Unlock()
-
 	apiVersion, kind := attributes.GVK(schema).ToAPIVersionAndKind()
-	ret := make(chan *summary.Relationship, 100)
-	cb := make(chan *summary.Relationship, 100)
-	id := cbID
-	cbID++
-	s.cbs[id] = cb
-
-	go func() {
-		defer close(ret)
-		for rel := range cb {
-			if rel.Kind == kind &&
-				rel.APIVersion == apiVersion &&
-				rel.Namespace == namespace {
-				ret <- rel
-			}
-		}
-	}()
-
-	go func() {
-		<-ctx.Done()
-		s.Lock()
-		defer s.Unlock()
-		close(cb)
-		delete(s.cbs, id)
-	}()
+	return s.subs.subscribe(ctx, subscriptionKey{
+		apiVersion: apiVersion,
+		kind:       kind,
+		namespace:  namespace,
+	})
+}
 
-	return cb
+// DroppedRelationshipChanges is the number of relationship changes dropped for slow watchers
+func (s *SummaryCache) DroppedRelationshipChanges() uint64 {
+	return s.subs.Dropped()
 }
 
 func (s *SummaryCache) SummaryAndRelationship(obj runtime.Object) (*summary.SummarizedObject, []Relationship) {
@@ -106,7 +108,7 @@ func (s *SummaryCache) SummaryAndRelationship(obj runtime.Object) (*summary.Summ
 	defer s.RUnlock()
 
 	key := toKey(obj)
-	summarized := summary.Summarized(obj)
+	summarized := s.summarize(obj)
 
 	relObjs, err := s.cache.ByIndex(relationshipIndex, key)
 	if err != nil {
@@ -142,6 +144,22 @@ func (s *SummaryCache) SummaryAndRelationship(obj runtime.Object) (*summary.Summ
 	return summarized, rels
 }
 
+// AddComputedFields sets metadata.state and metadata.relationships of obj from its summary
+func (s *SummaryCache) AddComputedFields(obj *unstructured.Unstructured) {
+	summarized, rels := s.SummaryAndRelationship(obj)
+	state := map[string]interface{}{
+		"name":          summarized.State,
+		"error":         summarized.Error,
+		"transitioning": summarized.Transitioning,
+		"message":       strings.Join(summarized.Message, ":"),
+	}
+	if health := s.Health(obj); health != nil {
+		state["health"] = health.ToMap()
+	}
+	data.PutValue(obj.Object, state, "metadata", "state")
+	data.PutValue(obj.Object, rels, "metadata", "relationships")
+}
+
 func (s *SummaryCache) reverseRel(summarized *summary.SummarizedObject, rel summary.Relationship) Relationship {
 	return s.toRel(summarized.Namespace, &summary.Relationship{
 		Name:       summarized.Name,
@@ -227,16 +245,11 @@ func (s *SummaryCache) Add(obj runtime.Object) {
 	for _, rel := range rels {
 		s.notify(rel)
 	}
+	s.updateRollup(summary, rels, nil, false)
 }
 
 func (s *SummaryCache) notify(rel *summary.Relationship) {
-	go func() {
-		s.Lock()
-		defer s.Unlock()
-		for _, cb := range s.cbs {
-			cb <- rel
-		}
-	}()
+	s.subs.publish(rel)
 }
 
 func (s *SummaryCache) Remove(obj runtime.Object) {
@@ -247,6 +260,7 @@ func (s *SummaryCache) Remove(obj runtime.Object) {
 	for _, rel := range rels {
 		s.notify(rel)
 	}
+	s.updateRollup(summary, rels, nil, true)
 }
 
 func (s *SummaryCache) Change(newObj, oldObj runtime.Object) {
@@ -262,12 +276,13 @@ func (s *SummaryCache) Change(newObj, oldObj runtime.Object) {
 		}
 	}
 	s.cache.Update(key, summary)
+	s.updateRollup(summary, rels, oldRels, false)
 }
 
 func (s *SummaryCache) process(obj runtime.Object) (*summary.SummarizedObject, []*summary.Relationship) {
 	var (
 		rels    []*summary.Relationship
-		summary = summary.Summarized(obj)
+		summary = s.summarize(obj)
 	)
 
 	for _, rel := range summary.Relationships {
@@ -300,6 +315,12 @@ func (s *SummaryCache) relationshipIndexer(obj interface{}) (result []string, er
 	return
 }
 
+// kindIndexer indexes objects by namespace and kind
+func kindIndexer(obj interface{}) ([]string, error) {
+	summary := obj.(*summary.SummarizedObject)
+	return []string{toKeyFrom(summary.Namespace, "", summary.GroupVersionKind())}, nil
+}
+
 func (s *SummaryCache) resolveNamespace(sourceNamespace, toNamespace string, gvk runtimeschema.GroupVersionKind) string {
 	if toNamespace != "" {
 		return toNamespace